@@ -3,17 +3,61 @@ package main
 import (
 	"flag"
 	"fmt"
+	"gba/pkg/capture"
+	"gba/pkg/gba"
 	"gba/pkg/gui"
+	"gba/pkg/ppu"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 func main() {
 	// 定义命令行参数
 	biosFile := flag.String("bios", "", "Path to GBA BIOS file (optional)")
+	saveDir := flag.String("savedir", "", "Directory for .sav files (default: next to the ROM)")
+	colorName := flag.String("color", "none", "Color correction: none, gba, gba-light")
+	screenshotDir := flag.String("screenshot-dir", "screenshots", "Directory for screenshots")
+	screenshotScale := flag.Int("screenshot-scale", 1, "Integer scale factor for screenshots")
+	screenshotAt := flag.Int("screenshot-at-frame", 0, "Run without GUI, save a screenshot at frame N and exit")
+	recordFile := flag.String("record", "", "Record video from startup to this file (.avi, or .y4m with a .wav track)")
+	recordFrames := flag.Int("record-frames", 0, "Run without GUI and stop after N frames (use with -record)")
+	recordDir := flag.String("record-dir", "recordings", "Directory for recordings started from the GUI")
+	recordFormat := flag.String("record-format", "avi", "Format for recordings started from the GUI: avi or y4m")
+	clipSeconds := flag.Float64("clip-seconds", gba.DefaultClipSeconds, "Seconds of gameplay kept for GIF clips (0 disables)")
 	flag.Parse()
 
+	profile, err := ppu.ParseColorProfile(*colorName)
+	if err != nil {
+		fmt.Printf("Warning: %v, using none\n", err)
+	}
+
+	shots := capture.NewScreenshotter(*screenshotDir)
+	shots.Scale = *screenshotScale
+
+	if *screenshotAt > 0 || (*recordFile != "" && *recordFrames > 0) {
+		os.Exit(runHeadless(headlessOptions{
+			biosFile:     *biosFile,
+			saveDir:      *saveDir,
+			profile:      profile,
+			shots:        shots,
+			screenshotAt: *screenshotAt,
+			recordFile:   *recordFile,
+			recordFrames: *recordFrames,
+		}))
+	}
+
 	// 创建主窗口
 	window := gui.NewMainWindow()
+	window.SetSaveDir(*saveDir)
+	window.SetColorProfile(profile)
+	window.SetScreenshotDir(*screenshotDir)
+	window.SetScreenshotScale(*screenshotScale)
+	window.SetRecordDir(*recordDir)
+	window.SetClipSeconds(*clipSeconds)
+	if err := window.SetRecordFormat(*recordFormat); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
 
 	// 如果提供了 BIOS 文件，先加载
 	if *biosFile != "" {
@@ -41,6 +85,11 @@ func main() {
 			// 继续运行，用户可以从菜单打开文件
 		} else {
 			fmt.Printf("Loaded ROM: %s\n", romFile)
+			if *recordFile != "" {
+				if err := window.StartRecordingTo(*recordFile); err != nil {
+					fmt.Printf("Failed to start recording: %v\n", err)
+				}
+			}
 		}
 	}
 
@@ -48,17 +97,104 @@ func main() {
 	window.Start()
 }
 
+type headlessOptions struct {
+	biosFile     string
+	saveDir      string
+	profile      ppu.ColorProfile
+	shots        *capture.Screenshotter
+	screenshotAt int
+	recordFile   string
+	recordFrames int
+}
+
+// runHeadless 不创建窗口，运行到截图帧或录像帧数后退出，返回退出码
+func runHeadless(opts headlessOptions) int {
+	args := flag.Args()
+	if len(args) < 1 {
+		fmt.Println("-screenshot-at-frame and -record-frames require a ROM file")
+		return 2
+	}
+	romFile := args[0]
+
+	emulator := gba.New()
+	emulator.SaveDir = opts.saveDir
+	emulator.PPU.SetColorProfile(opts.profile)
+
+	if opts.biosFile != "" {
+		if err := emulator.LoadBIOS(opts.biosFile); err != nil {
+			fmt.Printf("Warning: Failed to load BIOS: %v\n", err)
+		}
+	}
+	if err := emulator.LoadROM(romFile); err != nil {
+		fmt.Printf("Failed to load ROM: %v\n", err)
+		return 1
+	}
+	opts.shots.Prefix = strings.TrimSuffix(filepath.Base(romFile), filepath.Ext(romFile))
+
+	if opts.recordFile != "" {
+		if err := emulator.StartRecording(opts.recordFile); err != nil {
+			fmt.Printf("Failed to start recording: %v\n", err)
+			return 1
+		}
+	}
+
+	lastFrame := opts.screenshotAt
+	if opts.recordFrames > lastFrame {
+		lastFrame = opts.recordFrames
+	}
+
+	status := 0
+	for emulator.GetFrameCount() < lastFrame {
+		emulator.Step()
+
+		if opts.screenshotAt > 0 && emulator.GetFrameCount() == opts.screenshotAt {
+			img, seq := emulator.PPU.FrameImage()
+			path, err := opts.shots.Save(img, seq)
+			if err != nil {
+				fmt.Printf("Failed to save screenshot: %v\n", err)
+				status = 1
+			} else {
+				fmt.Printf("Screenshot saved: %s\n", path)
+			}
+			opts.screenshotAt = 0
+		}
+	}
+
+	if err := emulator.StopRecording(); err != nil {
+		fmt.Printf("%v\n", err)
+		status = 1
+	}
+	if err := emulator.FlushSave(); err != nil {
+		fmt.Printf("Failed to write save file: %v\n", err)
+	}
+	return status
+}
+
 func printHelp() {
 	fmt.Println("GBA Emulator with Fyne GUI")
 	fmt.Println("Usage: gba [options] <rom_file.gba>")
 	fmt.Println("")
 	fmt.Println("Options:")
 	fmt.Println("  -bios string    Path to GBA BIOS file (optional)")
+	fmt.Println("  -savedir string Directory for .sav files (default: next to the ROM)")
+	fmt.Println("  -color string   Color correction: none, gba, gba-light (default none)")
+	fmt.Println("  -screenshot-dir string    Directory for screenshots (default screenshots)")
+	fmt.Println("  -screenshot-scale int     Integer scale factor for screenshots (default 1)")
+	fmt.Println("  -screenshot-at-frame int  Run without GUI, save a screenshot at frame N and exit")
+	fmt.Println("  -record string            Record video from startup (.avi, or .y4m with a .wav track)")
+	fmt.Println("  -record-frames int        Run without GUI and stop after N frames (use with -record)")
+	fmt.Println("  -record-dir string        Directory for recordings started from the GUI (default recordings)")
+	fmt.Println("  -record-format string     Format for recordings started from the GUI: avi or y4m (default avi)")
+	fmt.Println("  -clip-seconds float       Seconds of gameplay kept for GIF clips, 0 disables (default 5)")
 	fmt.Println("  -h, --help      Show this help message")
 	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  gba game.gba")
 	fmt.Println("  gba -bios gba_bios.bin game.gba")
+	fmt.Println("  gba -savedir saves game.gba")
+	fmt.Println("  gba -color gba game.gba")
+	fmt.Println("  gba -screenshot-at-frame 600 game.gba")
+	fmt.Println("  gba -record run.y4m -record-frames 3600 game.gba")
 	fmt.Println("")
 	fmt.Println("Keyboard controls:")
 	fmt.Println("  Z         - A button")
@@ -68,6 +204,8 @@ func printHelp() {
 	fmt.Println("  Arrow keys - D-Pad")
 	fmt.Println("  A         - L shoulder")
 	fmt.Println("  S         - R shoulder")
+	fmt.Println("  F12       - Screenshot")
+	fmt.Println("  F10       - Save GIF clip of the last few seconds")
 	fmt.Println("")
 	fmt.Println("You can also use File -> Open ROM from the menu")
 }
@@ -1,8 +1,22 @@
 package apu
 
+import "sync"
+
 const (
-	SampleRate = 32768
-	BufferSize = 4096
+	CPUFrequency = 16777216
+	SampleRate   = 32768
+	BufferSize   = 4096
+)
+
+// SOUNDCNT_X 位域
+const (
+	soundMasterEnable = 0x0080
+)
+
+// SOUNDCNT_H 高字节的 FIFO 复位位，写 1 清空对应 FIFO，读回总是 0
+const (
+	fifoAReset = 0x08
+	fifoBReset = 0x80
 )
 
 type APU struct {
@@ -21,7 +35,8 @@ type APU struct {
 	SOUNDCNT_X  uint16
 	SOUNDBIAS   uint16
 
-	WAVE_RAM [16]byte
+	// 波形 RAM 两组，CPU 访问的是通道 3 没有在播放的那一组
+	WAVE_RAM [2][16]byte
 	FIFO_A   [32]byte
 	FIFO_B   [32]byte
 
@@ -35,14 +50,25 @@ type APU struct {
 	CycleCount  int
 	SampleCount int
 
+	// 录像可以在 GUI 线程清空缓冲，BufferPos 的读写需要持有 bufferMu
 	SoundBuffer []int16
 	BufferPos   int
+	bufferMu    sync.Mutex
+
+	ch1 *squareChannel
+	ch2 *squareChannel
+	ch3 *waveChannel
+	ch4 *noiseChannel
+
+	frameCycles int
+	frameStep   int
 }
 
 func New() *APU {
 	apu := &APU{
 		SoundBuffer: make([]int16, BufferSize*2),
 	}
+	apu.resetPSG()
 	apu.Reset()
 	return apu
 }
@@ -63,55 +89,155 @@ func (a *APU) Reset() {
 	a.SOUNDCNT_X = 0
 	a.SOUNDBIAS = 0x0200
 
-	a.FIFOACount = 0
-	a.FIFO_B_Count = 0
-	a.FIFO_A_Read = 0
-	a.FIFO_A_Write = 0
-	a.FIFO_B_Read = 0
-	a.FIFO_B_Write = 0
+	a.resetFIFOA()
+	a.resetFIFOB()
 
 	a.CycleCount = 0
 	a.SampleCount = 0
-	a.BufferPos = 0
+	a.ClearBuffer()
+	a.resetPSG()
 
 	for i := range a.SoundBuffer {
 		a.SoundBuffer[i] = 0
 	}
 }
 
+// resetPSG 复位 PSG 通道和帧序列器，关闭总开关时也会调用
+func (a *APU) resetPSG() {
+	a.ch1 = newSquareChannel(true)
+	a.ch2 = newSquareChannel(false)
+	a.ch3 = newWaveChannel(&a.WAVE_RAM)
+	a.ch4 = newNoiseChannel()
+	a.frameCycles = 0
+	a.frameStep = 0
+}
+
 func (a *APU) Step(cycles int) {
-	a.CycleCount += cycles
+	cyclesPerSample := CPUFrequency / SampleRate
 
-	cyclesPerSample := 16777216 / SampleRate
+	// 按采样点分段推进通道，采样时取各通道当前输出
+	for cycles > 0 {
+		n := cyclesPerSample - a.CycleCount
+		if n > cycles {
+			n = cycles
+		}
+		a.clockPSG(n)
+		a.CycleCount += n
+		cycles -= n
 
-	for a.CycleCount >= cyclesPerSample {
-		a.CycleCount -= cyclesPerSample
-		a.generateSample()
+		if a.CycleCount >= cyclesPerSample {
+			a.CycleCount -= cyclesPerSample
+			a.generateSample()
+		}
 	}
 }
 
+func (a *APU) clockPSG(cycles int) {
+	if a.SOUNDCNT_X&soundMasterEnable == 0 {
+		return
+	}
+
+	a.ch1.step(cycles)
+	a.ch2.step(cycles)
+	a.ch3.step(cycles)
+	a.ch4.step(cycles)
+
+	a.frameCycles += cycles
+	for a.frameCycles >= frameSequencerCycles {
+		a.frameCycles -= frameSequencerCycles
+		a.clockFrameSequencer()
+	}
+}
+
+// clockFrameSequencer 第 0/2/4/6 步驱动长度计数器，第 2/6 步驱动扫频，第 7 步驱动包络
+func (a *APU) clockFrameSequencer() {
+	switch a.frameStep {
+	case 0, 4:
+		a.clockLength()
+	case 2, 6:
+		a.clockLength()
+		a.ch1.clockSweep()
+	case 7:
+		a.ch1.env.clock()
+		a.ch2.env.clock()
+		a.ch4.env.clock()
+	}
+	a.frameStep = (a.frameStep + 1) & 7
+}
+
+func (a *APU) clockLength() {
+	a.ch1.clockLength()
+	a.ch2.clockLength()
+	a.ch3.clockLength()
+	a.ch4.clockLength()
+}
+
 func (a *APU) generateSample() {
 	left := int16(0)
 	right := int16(0)
 
-	if a.SOUNDCNT_X&0x0080 != 0 {
+	if a.SOUNDCNT_X&soundMasterEnable != 0 {
 		left = a.generateLeftChannel()
 		right = a.generateRightChannel()
 	}
 
+	a.bufferMu.Lock()
 	if a.BufferPos < len(a.SoundBuffer)-1 {
 		a.SoundBuffer[a.BufferPos] = left
 		a.SoundBuffer[a.BufferPos+1] = right
 		a.BufferPos += 2
 	}
+	a.bufferMu.Unlock()
 }
 
 func (a *APU) generateLeftChannel() int16 {
-	return 0
+	return a.mixPSG(a.SOUNDCNT_L>>12, (a.SOUNDCNT_L>>4)&0x7)
 }
 
 func (a *APU) generateRightChannel() int16 {
-	return 0
+	return a.mixPSG(a.SOUNDCNT_L>>8, a.SOUNDCNT_L&0x7)
+}
+
+// mixPSG 按 SOUNDCNT_L 的通道开关和主音量、SOUNDCNT_H 的 PSG 比例混合 PSG 通道
+func (a *APU) mixPSG(enable, volume uint16) int16 {
+	outputs := [...]int{a.ch1.output(), a.ch2.output(), a.ch3.output(), a.ch4.output()}
+
+	sum := 0
+	for i, out := range outputs {
+		if enable&(1<<uint(i)) != 0 {
+			sum += out
+		}
+	}
+	sum *= int(volume) + 1
+
+	// 0 = 25%, 1 = 50%, 2 = 100%, 3 无效
+	switch a.SOUNDCNT_H & 0x3 {
+	case 0:
+		sum >>= 2
+	case 1:
+		sum >>= 1
+	}
+
+	// 四个通道满音量时为 15*4*8 = 480，放大到 int16 的一半左右，给 DMA 声道留出余量
+	return int16(sum * 32)
+}
+
+// channelStatus 返回 SOUNDCNT_X bit0-3 的通道工作状态
+func (a *APU) channelStatus() uint16 {
+	var status uint16
+	if a.ch1.enabled {
+		status |= 0x1
+	}
+	if a.ch2.enabled {
+		status |= 0x2
+	}
+	if a.ch3.enabled {
+		status |= 0x4
+	}
+	if a.ch4.enabled {
+		status |= 0x8
+	}
+	return status
 }
 
 func (a *APU) WriteFIFO_A(data uint32) {
@@ -136,6 +262,18 @@ func (a *APU) WriteFIFO_B(data uint32) {
 	}
 }
 
+func (a *APU) resetFIFOA() {
+	a.FIFOACount = 0
+	a.FIFO_A_Read = 0
+	a.FIFO_A_Write = 0
+}
+
+func (a *APU) resetFIFOB() {
+	a.FIFO_B_Count = 0
+	a.FIFO_B_Read = 0
+	a.FIFO_B_Write = 0
+}
+
 func (a *APU) ReadFIFO_A() byte {
 	if a.FIFOACount == 0 {
 		return 0
@@ -157,90 +295,199 @@ func (a *APU) ReadFIFO_B() byte {
 }
 
 func (a *APU) GetSamples() []int16 {
+	a.bufferMu.Lock()
+	defer a.bufferMu.Unlock()
 	return a.SoundBuffer[:a.BufferPos]
 }
 
+// ClearBuffer 丢弃已生成的样本，可在任意线程调用
 func (a *APU) ClearBuffer() {
+	a.bufferMu.Lock()
 	a.BufferPos = 0
+	a.bufferMu.Unlock()
+}
+
+// 各声音寄存器游戏可读的位，长度、频率和重新开始位只写
+var soundReadMasks = map[uint32]uint16{
+	0x04000060: 0x007F,
+	0x04000062: 0xFFC0,
+	0x04000064: 0x4000,
+	0x04000068: 0xFFC0,
+	0x0400006C: 0x4000,
+	0x04000070: 0x00E0,
+	0x04000072: 0xE000,
+	0x04000074: 0x4000,
+	0x04000078: 0xFF00,
+	0x0400007C: 0x40FF,
+	0x04000080: 0xFF77,
+	0x04000082: 0x770F,
+	0x04000088: 0xFFFF,
 }
 
 func (a *APU) ReadRegister(addr uint32) uint16 {
+	var val uint16
 	switch addr {
 	case 0x04000060:
-		return a.SOUND1CNT_L
+		val = a.SOUND1CNT_L
 	case 0x04000062:
-		return a.SOUND1CNT_H
+		val = a.SOUND1CNT_H
 	case 0x04000064:
-		return a.SOUND1CNT_X
+		val = a.SOUND1CNT_X
 	case 0x04000068:
-		return a.SOUND2CNT_L
+		val = a.SOUND2CNT_L
 	case 0x0400006C:
-		return a.SOUND2CNT_H
+		val = a.SOUND2CNT_H
 	case 0x04000070:
-		return a.SOUND3CNT_L
+		val = a.SOUND3CNT_L
 	case 0x04000072:
-		return a.SOUND3CNT_H
+		val = a.SOUND3CNT_H
 	case 0x04000074:
-		return a.SOUND3CNT_X
+		val = a.SOUND3CNT_X
 	case 0x04000078:
-		return a.SOUND4CNT_L
+		val = a.SOUND4CNT_L
 	case 0x0400007C:
-		return a.SOUND4CNT_H
+		val = a.SOUND4CNT_H
 	case 0x04000080:
-		return a.SOUNDCNT_L
+		val = a.SOUNDCNT_L
 	case 0x04000082:
-		return a.SOUNDCNT_H
+		val = a.SOUNDCNT_H
 	case 0x04000084:
-		return a.SOUNDCNT_X
+		return a.SOUNDCNT_X&soundMasterEnable | a.channelStatus()
 	case 0x04000088:
-		return a.SOUNDBIAS
+		val = a.SOUNDBIAS
 	default:
+		if addr >= 0x04000090 && addr < 0x040000A0 {
+			return uint16(a.ReadWAVE_RAM(addr)) | uint16(a.ReadWAVE_RAM(addr+1))<<8
+		}
 		return 0
 	}
+	return val & soundReadMasks[addr]
 }
 
 func (a *APU) WriteRegister(addr uint32, val uint16) {
+	a.WriteRegister8(addr, uint8(val))
+	a.WriteRegister8(addr+1, uint8(val>>8))
+}
+
+// setByte 更新 16 位寄存器镜像中 addr 对应的字节
+func setByte(reg *uint16, addr uint32, val uint8) {
+	if addr&1 == 0 {
+		*reg = *reg&0xFF00 | uint16(val)
+	} else {
+		*reg = *reg&0x00FF | uint16(val)<<8
+	}
+}
+
+// WriteRegister8 按字节写声音寄存器。重新开始等副作用只在写对应字节时发生，
+// 因此 MMU 的字节写入不能用读-改-写代替
+func (a *APU) WriteRegister8(addr uint32, val uint8) {
+	if addr >= 0x04000090 && addr < 0x040000A0 {
+		a.WriteWAVE_RAM(addr, val)
+		return
+	}
+
+	// 总开关关闭时 PSG 寄存器不可写
+	if a.SOUNDCNT_X&soundMasterEnable == 0 && addr < 0x04000082 {
+		return
+	}
+
 	switch addr {
 	case 0x04000060:
-		a.SOUND1CNT_L = val
+		setByte(&a.SOUND1CNT_L, addr, val)
+		a.ch1.writeSweep(val)
 	case 0x04000062:
-		a.SOUND1CNT_H = val
+		setByte(&a.SOUND1CNT_H, addr, val)
+		a.ch1.writeDutyLength(val)
+	case 0x04000063:
+		setByte(&a.SOUND1CNT_H, addr, val)
+		a.ch1.writeEnvelope(val)
 	case 0x04000064:
-		a.SOUND1CNT_X = val
+		setByte(&a.SOUND1CNT_X, addr, val)
+		a.ch1.writeFreqLow(val)
+	case 0x04000065:
+		setByte(&a.SOUND1CNT_X, addr, val&0x7F)
+		a.ch1.writeFreqHigh(val)
 	case 0x04000068:
-		a.SOUND2CNT_L = val
+		setByte(&a.SOUND2CNT_L, addr, val)
+		a.ch2.writeDutyLength(val)
+	case 0x04000069:
+		setByte(&a.SOUND2CNT_L, addr, val)
+		a.ch2.writeEnvelope(val)
 	case 0x0400006C:
-		a.SOUND2CNT_H = val
+		setByte(&a.SOUND2CNT_H, addr, val)
+		a.ch2.writeFreqLow(val)
+	case 0x0400006D:
+		setByte(&a.SOUND2CNT_H, addr, val&0x7F)
+		a.ch2.writeFreqHigh(val)
 	case 0x04000070:
-		a.SOUND3CNT_L = val
+		setByte(&a.SOUND3CNT_L, addr, val)
+		a.ch3.writeControl(val)
 	case 0x04000072:
-		a.SOUND3CNT_H = val
+		setByte(&a.SOUND3CNT_H, addr, val)
+		a.ch3.writeLength(val)
+	case 0x04000073:
+		setByte(&a.SOUND3CNT_H, addr, val)
+		a.ch3.writeVolume(val)
 	case 0x04000074:
-		a.SOUND3CNT_X = val
+		setByte(&a.SOUND3CNT_X, addr, val)
+		a.ch3.writeFreqLow(val)
+	case 0x04000075:
+		setByte(&a.SOUND3CNT_X, addr, val&0x7F)
+		a.ch3.writeFreqHigh(val)
 	case 0x04000078:
-		a.SOUND4CNT_L = val
+		setByte(&a.SOUND4CNT_L, addr, val)
+		a.ch4.writeLength(val)
+	case 0x04000079:
+		setByte(&a.SOUND4CNT_L, addr, val)
+		a.ch4.writeEnvelope(val)
 	case 0x0400007C:
-		a.SOUND4CNT_H = val
-	case 0x04000080:
-		a.SOUNDCNT_L = val
+		setByte(&a.SOUND4CNT_H, addr, val)
+		a.ch4.writeFrequency(val)
+	case 0x0400007D:
+		setByte(&a.SOUND4CNT_H, addr, val&0x7F)
+		a.ch4.writeControl(val)
+	case 0x04000080, 0x04000081:
+		setByte(&a.SOUNDCNT_L, addr, val)
 	case 0x04000082:
-		a.SOUNDCNT_H = val
+		setByte(&a.SOUNDCNT_H, addr, val)
+	case 0x04000083:
+		if val&fifoAReset != 0 {
+			a.resetFIFOA()
+		}
+		if val&fifoBReset != 0 {
+			a.resetFIFOB()
+		}
+		setByte(&a.SOUNDCNT_H, addr, val&^(fifoAReset|fifoBReset))
 	case 0x04000084:
-		a.SOUNDCNT_X = val
-	case 0x04000088:
-		a.SOUNDBIAS = val
+		a.writeSoundCntX(val)
+	case 0x04000088, 0x04000089:
+		setByte(&a.SOUNDBIAS, addr, val)
+	}
+}
+
+// writeSoundCntX 处理总开关，关闭时 PSG 寄存器和通道全部清零
+func (a *APU) writeSoundCntX(val uint8) {
+	if val&soundMasterEnable == 0 && a.SOUNDCNT_X&soundMasterEnable != 0 {
+		a.SOUND1CNT_L, a.SOUND1CNT_H, a.SOUND1CNT_X = 0, 0, 0
+		a.SOUND2CNT_L, a.SOUND2CNT_H = 0, 0
+		a.SOUND3CNT_L, a.SOUND3CNT_H, a.SOUND3CNT_X = 0, 0, 0
+		a.SOUND4CNT_L, a.SOUND4CNT_H = 0, 0
+		a.SOUNDCNT_L = 0
+		a.resetPSG()
 	}
+	a.SOUNDCNT_X = uint16(val) & soundMasterEnable
 }
 
+// WriteWAVE_RAM 写入 CPU 当前可访问的波形 RAM 组
 func (a *APU) WriteWAVE_RAM(addr uint32, val uint8) {
 	if addr >= 0x04000090 && addr < 0x040000A0 {
-		a.WAVE_RAM[addr-0x04000090] = val
+		a.WAVE_RAM[a.ch3.cpuBank()][addr-0x04000090] = val
 	}
 }
 
 func (a *APU) ReadWAVE_RAM(addr uint32) uint8 {
 	if addr >= 0x04000090 && addr < 0x040000A0 {
-		return a.WAVE_RAM[addr-0x04000090]
+		return a.WAVE_RAM[a.ch3.cpuBank()][addr-0x04000090]
 	}
 	return 0
 }
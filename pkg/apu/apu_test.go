@@ -0,0 +1,68 @@
+package apu
+
+import "testing"
+
+// newTestAPU 返回打开总开关的 APU
+func newTestAPU() *APU {
+	a := New()
+	a.WriteRegister8(0x04000084, soundMasterEnable)
+	return a
+}
+
+// ticks 推进 n 次帧序列器。第 k 次（从 1 数）执行第 (k-1)%8 步：
+// 奇数次驱动长度计数器，第 3、7 次起每 4 次驱动扫频，每 8 次驱动包络
+func (a *APU) ticks(n int) {
+	for i := 0; i < n; i++ {
+		a.Step(frameSequencerCycles)
+	}
+}
+
+// regWrite 是一次字节寄存器写入
+type regWrite struct {
+	addr uint32
+	val  uint8
+}
+
+func (a *APU) apply(writes []regWrite) {
+	for _, w := range writes {
+		a.WriteRegister8(w.addr, w.val)
+	}
+}
+
+func (a *APU) status() uint16 {
+	return a.ReadRegister(0x04000084) & 0xF
+}
+
+func TestSoundCntHFIFOReset(t *testing.T) {
+	a := newTestAPU()
+	a.WriteFIFO_A(0x04030201)
+	a.WriteFIFO_A(0x08070605)
+	a.WriteFIFO_B(0x44332211)
+	a.ReadFIFO_A()
+
+	// 只复位 FIFO A，复位位不会保存
+	a.WriteRegister(0x04000082, 0x0800|0x0302)
+	if a.FIFOACount != 0 || a.ReadFIFO_A() != 0 {
+		t.Errorf("FIFO A holds %d bytes after reset", a.FIFOACount)
+	}
+	if a.FIFO_B_Count != 4 {
+		t.Errorf("FIFO B holds %d bytes, want 4", a.FIFO_B_Count)
+	}
+	if a.SOUNDCNT_H != 0x0302 {
+		t.Errorf("SOUNDCNT_H = %04X, want 0302", a.SOUNDCNT_H)
+	}
+
+	// 复位后从缓冲头部重新写入
+	a.WriteFIFO_A(0x0D0C0B0A)
+	if got := a.ReadFIFO_A(); got != 0x0A {
+		t.Errorf("first byte after reset = %02X, want 0A", got)
+	}
+
+	a.WriteRegister8(0x04000083, fifoBReset)
+	if a.FIFO_B_Count != 0 || a.ReadFIFO_B() != 0 {
+		t.Errorf("FIFO B holds %d bytes after reset", a.FIFO_B_Count)
+	}
+	if got := a.ReadRegister(0x04000082); got != 0x0002 {
+		t.Errorf("SOUNDCNT_H reads %04X, want 0002", got)
+	}
+}
@@ -0,0 +1,96 @@
+package apu
+
+// 噪声通道分频比，对应 r = 0..7（r = 0 视为 0.5），单位为 CPU 周期
+var noiseDivisors = [8]int{32, 64, 128, 192, 256, 320, 384, 448}
+
+// noiseChannel 是通道 4，用线性反馈移位寄存器产生伪随机噪声
+type noiseChannel struct {
+	enabled bool
+	timer   int
+
+	divisor uint8
+	shift   uint8
+	width7  bool
+	lfsr    uint16
+
+	length lengthCounter
+	env    envelope
+}
+
+func newNoiseChannel() *noiseChannel {
+	return &noiseChannel{length: lengthCounter{max: 64}, lfsr: 0x7FFF}
+}
+
+// period 返回 LFSR 每次移位的 CPU 周期数，频率为 524288/r/2^(s+1) Hz
+func (c *noiseChannel) period() int {
+	return noiseDivisors[c.divisor] << c.shift
+}
+
+func (c *noiseChannel) writeLength(val uint8) {
+	c.length.load(int(val & 0x3F))
+}
+
+func (c *noiseChannel) writeEnvelope(val uint8) {
+	c.env.write(val)
+	if !c.env.dacEnabled() {
+		c.enabled = false
+	}
+}
+
+// writeFrequency 处理 SOUND4CNT_H 低字节：bit0-2 分频比，bit3 7 位模式，bit4-7 移位时钟
+func (c *noiseChannel) writeFrequency(val uint8) {
+	c.divisor = val & 0x7
+	c.width7 = val&0x8 != 0
+	c.shift = val >> 4
+}
+
+// writeControl 处理 SOUND4CNT_H 高字节：bit6 长度使能，bit7 重新开始
+func (c *noiseChannel) writeControl(val uint8) {
+	c.length.enabled = val&0x40 != 0
+	if val&0x80 != 0 {
+		c.trigger()
+	}
+}
+
+func (c *noiseChannel) trigger() {
+	c.enabled = c.env.dacEnabled()
+	c.length.trigger()
+	c.timer = c.period()
+	c.env.trigger()
+	c.lfsr = 0x7FFF
+}
+
+func (c *noiseChannel) clockLength() {
+	if c.length.clock() {
+		c.enabled = false
+	}
+}
+
+func (c *noiseChannel) step(cycles int) {
+	// 移位时钟 14、15 时不再移位
+	if !c.enabled || c.shift >= 14 {
+		return
+	}
+
+	c.timer -= cycles
+	for c.timer <= 0 {
+		c.timer += c.period()
+
+		bit := (c.lfsr ^ c.lfsr>>1) & 1
+		c.lfsr = c.lfsr>>1 | bit<<14
+		if c.width7 {
+			c.lfsr = c.lfsr&^0x40 | bit<<6
+		}
+	}
+}
+
+// output 返回 -15..15 的有符号输出，LFSR 最低位为 0 时输出高电平
+func (c *noiseChannel) output() int {
+	if !c.enabled {
+		return 0
+	}
+	if c.lfsr&1 == 0 {
+		return int(c.env.volume)
+	}
+	return -int(c.env.volume)
+}
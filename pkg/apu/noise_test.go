@@ -0,0 +1,102 @@
+package apu
+
+import "testing"
+
+// shiftNoise 以每次 32 周期的最快频率推进 n 次 LFSR，返回每次移位后的最低位
+func shiftNoise(width7 bool, n int) []byte {
+	a := newTestAPU()
+	freq := uint8(0)
+	if width7 {
+		freq = 0x08
+	}
+	a.apply([]regWrite{{0x04000079, 0xF0}, {0x0400007C, freq}, {0x0400007D, 0x80}})
+
+	out := make([]byte, n)
+	for i := range out {
+		a.Step(32)
+		out[i] = '0' + byte(a.ch4.lfsr&1)
+	}
+	return out
+}
+
+func TestNoiseLFSRSequence(t *testing.T) {
+	tests := []struct {
+		name   string
+		width7 bool
+		prefix string
+		period int
+	}{
+		// 从全 1 开始，反馈为最低两位的异或
+		{"15-bit", false, "1111111111111100000000000000100000000000", 32767},
+		{"7-bit", true, "1111110000001000001100001010001111001000", 127},
+	}
+
+	for _, tt := range tests {
+		bits := shiftNoise(tt.width7, 3*tt.period)
+		if got := string(bits[:len(tt.prefix)]); got != tt.prefix {
+			t.Errorf("%s: first bits %s, want %s", tt.name, got, tt.prefix)
+		}
+
+		// 最大长度序列：周期为 2^n-1，没有更短的重复
+		for i := 0; i < tt.period; i++ {
+			if bits[i] != bits[i+tt.period] {
+				t.Errorf("%s: bit %d differs one period later", tt.name, i)
+				break
+			}
+		}
+		for p := 1; p < tt.period; p++ {
+			same := true
+			for i := 0; i < tt.period && same; i++ {
+				same = bits[i] == bits[i+p]
+			}
+			if same {
+				t.Errorf("%s: sequence repeats after %d shifts", tt.name, p)
+				break
+			}
+		}
+	}
+}
+
+func TestNoisePeriod(t *testing.T) {
+	tests := []struct {
+		freq   uint8
+		cycles int
+	}{
+		// r = 0 视为 0.5：524288 / 0.5 / 2 Hz，每 32 周期移位一次
+		{0x00, 32},
+		{0x01, 64},
+		{0x07, 448},
+		{0x10, 64},
+		{0x37, 448 << 3},
+		{0xD2, 128 << 13},
+		// 移位时钟 14、15 不再移位
+		{0xE0, 0},
+		{0xF3, 0},
+	}
+
+	for _, tt := range tests {
+		a := newTestAPU()
+		a.apply([]regWrite{{0x04000079, 0xF0}, {0x0400007C, tt.freq}, {0x0400007D, 0x80}})
+
+		if tt.cycles == 0 {
+			a.Step(1 << 20)
+			if a.ch4.lfsr != 0x7FFF {
+				t.Errorf("freq %02X: LFSR shifted to %04X", tt.freq, a.ch4.lfsr)
+			}
+			continue
+		}
+
+		a.Step(tt.cycles - 1)
+		if a.ch4.lfsr != 0x7FFF {
+			t.Errorf("freq %02X: LFSR shifted before %d cycles", tt.freq, tt.cycles)
+		}
+		a.Step(1)
+		if a.ch4.lfsr != 0x3FFF {
+			t.Errorf("freq %02X: LFSR = %04X after %d cycles, want 3FFF", tt.freq, a.ch4.lfsr, tt.cycles)
+		}
+		a.Step(tt.cycles)
+		if a.ch4.lfsr != 0x1FFF {
+			t.Errorf("freq %02X: LFSR = %04X after two periods, want 1FFF", tt.freq, a.ch4.lfsr)
+		}
+	}
+}
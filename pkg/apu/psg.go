@@ -0,0 +1,229 @@
+package apu
+
+// 帧序列器以 512 Hz 驱动长度计数器（256 Hz）、扫频（128 Hz）和音量包络（64 Hz）
+const frameSequencerCycles = CPUFrequency / 512
+
+// 方波四种占空比（12.5%、25%、50%、75%），每个周期 8 步
+var dutyTable = [4][8]uint8{
+	{0, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 0, 0, 0, 0, 0, 1},
+	{1, 0, 0, 0, 0, 1, 1, 1},
+	{0, 1, 1, 1, 1, 1, 1, 0},
+}
+
+// envelope 是通道 1/2/4 共用的音量包络
+type envelope struct {
+	initial  uint8
+	increase bool
+	period   uint8
+
+	volume uint8
+	timer  uint8
+}
+
+// write 处理 SOUNDxCNT 的包络字节：bit0-2 步长，bit3 方向，bit4-7 初始音量
+func (e *envelope) write(val uint8) {
+	e.period = val & 0x7
+	e.increase = val&0x8 != 0
+	e.initial = val >> 4
+}
+
+// dacEnabled 初始音量为 0 且方向为减小时通道被关闭
+func (e *envelope) dacEnabled() bool {
+	return e.initial != 0 || e.increase
+}
+
+func (e *envelope) trigger() {
+	e.volume = e.initial
+	e.timer = e.period
+}
+
+func (e *envelope) clock() {
+	if e.period == 0 {
+		return
+	}
+	if e.timer > 0 {
+		e.timer--
+	}
+	if e.timer != 0 {
+		return
+	}
+	e.timer = e.period
+
+	if e.increase && e.volume < 15 {
+		e.volume++
+	} else if !e.increase && e.volume > 0 {
+		e.volume--
+	}
+}
+
+// lengthCounter 计数到 0 时关闭通道，max 为 64（通道 1/2/4）或 256（通道 3）
+type lengthCounter struct {
+	max     int
+	counter int
+	enabled bool
+}
+
+func (l *lengthCounter) load(n int) {
+	l.counter = l.max - n
+}
+
+func (l *lengthCounter) trigger() {
+	if l.counter == 0 {
+		l.counter = l.max
+	}
+}
+
+// clock 返回通道是否应被关闭
+func (l *lengthCounter) clock() bool {
+	if !l.enabled || l.counter == 0 {
+		return false
+	}
+	l.counter--
+	return l.counter == 0
+}
+
+// squareChannel 是通道 1 和通道 2，通道 1 额外有扫频单元
+type squareChannel struct {
+	enabled bool
+	freq    uint16
+	duty    uint8
+	dutyPos uint8
+	timer   int
+
+	length lengthCounter
+	env    envelope
+
+	hasSweep      bool
+	sweepPeriod   uint8
+	sweepDecrease bool
+	sweepShift    uint8
+	sweepTimer    uint8
+	sweepEnabled  bool
+	shadowFreq    uint16
+}
+
+func newSquareChannel(hasSweep bool) *squareChannel {
+	return &squareChannel{hasSweep: hasSweep, length: lengthCounter{max: 64}}
+}
+
+// period 返回占空比每一步的 CPU 周期数，一个完整波形频率为 131072/(2048-n) Hz
+func (c *squareChannel) period() int {
+	return (2048 - int(c.freq)) * 16
+}
+
+// writeSweep 处理 SOUND1CNT_L：bit0-2 移位数，bit3 方向（1 为降频），bit4-6 扫频周期
+func (c *squareChannel) writeSweep(val uint8) {
+	c.sweepShift = val & 0x7
+	c.sweepDecrease = val&0x8 != 0
+	c.sweepPeriod = (val >> 4) & 0x7
+}
+
+// writeDutyLength 处理长度/占空比字节：bit0-5 长度，bit6-7 占空比
+func (c *squareChannel) writeDutyLength(val uint8) {
+	c.length.load(int(val & 0x3F))
+	c.duty = val >> 6
+}
+
+func (c *squareChannel) writeEnvelope(val uint8) {
+	c.env.write(val)
+	if !c.env.dacEnabled() {
+		c.enabled = false
+	}
+}
+
+func (c *squareChannel) writeFreqLow(val uint8) {
+	c.freq = c.freq&0x700 | uint16(val)
+}
+
+// writeFreqHigh 处理频率高字节：bit0-2 频率高位，bit6 长度使能，bit7 重新开始
+func (c *squareChannel) writeFreqHigh(val uint8) {
+	c.freq = c.freq&0xFF | uint16(val&0x7)<<8
+	c.length.enabled = val&0x40 != 0
+	if val&0x80 != 0 {
+		c.trigger()
+	}
+}
+
+func (c *squareChannel) trigger() {
+	c.enabled = c.env.dacEnabled()
+	c.length.trigger()
+	c.timer = c.period()
+	c.env.trigger()
+
+	if c.hasSweep {
+		c.shadowFreq = c.freq
+		c.sweepTimer = c.sweepReload()
+		c.sweepEnabled = c.sweepPeriod != 0 || c.sweepShift != 0
+		if c.sweepShift != 0 {
+			c.sweepTarget()
+		}
+	}
+}
+
+func (c *squareChannel) sweepReload() uint8 {
+	if c.sweepPeriod == 0 {
+		return 8
+	}
+	return c.sweepPeriod
+}
+
+// sweepTarget 计算下一次扫频的频率，超过 2047 时关闭通道
+func (c *squareChannel) sweepTarget() uint16 {
+	delta := c.shadowFreq >> c.sweepShift
+	target := c.shadowFreq + delta
+	if c.sweepDecrease {
+		target = c.shadowFreq - delta
+	}
+	if target > 2047 {
+		c.enabled = false
+	}
+	return target
+}
+
+func (c *squareChannel) clockSweep() {
+	if c.sweepTimer > 0 {
+		c.sweepTimer--
+	}
+	if c.sweepTimer != 0 {
+		return
+	}
+	c.sweepTimer = c.sweepReload()
+
+	if !c.sweepEnabled || c.sweepPeriod == 0 {
+		return
+	}
+
+	target := c.sweepTarget()
+	if target <= 2047 && c.sweepShift != 0 {
+		c.shadowFreq = target
+		c.freq = target
+		// 写回后再检查一次溢出
+		c.sweepTarget()
+	}
+}
+
+func (c *squareChannel) clockLength() {
+	if c.length.clock() {
+		c.enabled = false
+	}
+}
+
+func (c *squareChannel) step(cycles int) {
+	c.timer -= cycles
+	for c.timer <= 0 {
+		c.timer += c.period()
+		c.dutyPos = (c.dutyPos + 1) & 7
+	}
+}
+
+// output 返回 -15..15 的有符号输出
+func (c *squareChannel) output() int {
+	if !c.enabled {
+		return 0
+	}
+	if dutyTable[c.duty][c.dutyPos] != 0 {
+		return int(c.env.volume)
+	}
+	return -int(c.env.volume)
+}
@@ -0,0 +1,172 @@
+package apu
+
+import "testing"
+
+func TestSweepOverflow(t *testing.T) {
+	tests := []struct {
+		name  string
+		sweep uint8
+		freq  uint16
+		// 重新开始后和第 3 次帧序列器步（第一次扫频）后通道是否仍在工作
+		afterTrigger bool
+		afterSweep   bool
+		wantFreq     uint16
+	}{
+		// 2000 + 1000 > 2047，重新开始时立即关闭
+		{"overflow on trigger", 1<<4 | 1, 2000, false, false, 2000},
+		// 1024 -> 1536，写回后再算 1536 + 768 溢出
+		{"overflow after write back", 1<<4 | 1, 1024, true, false, 1536},
+		{"no overflow", 1<<4 | 2, 1024, true, true, 1280},
+		{"decrease", 1<<4 | 0x8 | 1, 2000, true, true, 1000},
+		// 周期为 0 时不扫频，但重新开始时仍检查溢出
+		{"period 0", 1, 1024, true, true, 1024},
+		{"period 0 overflow", 1, 2000, false, false, 2000},
+	}
+
+	for _, tt := range tests {
+		a := newTestAPU()
+		a.apply([]regWrite{
+			{0x04000060, tt.sweep},
+			{0x04000063, 0xF0},
+			{0x04000064, uint8(tt.freq)},
+			{0x04000065, uint8(tt.freq>>8) | 0x80},
+		})
+		if got := a.status()&1 != 0; got != tt.afterTrigger {
+			t.Errorf("%s: enabled after trigger = %v, want %v", tt.name, got, tt.afterTrigger)
+		}
+		a.ticks(3)
+		if got := a.status()&1 != 0; got != tt.afterSweep {
+			t.Errorf("%s: enabled after sweep = %v, want %v", tt.name, got, tt.afterSweep)
+		}
+		if a.ch1.freq != tt.wantFreq {
+			t.Errorf("%s: frequency %d, want %d", tt.name, a.ch1.freq, tt.wantFreq)
+		}
+	}
+}
+
+func TestLengthExpiry(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []regWrite
+		bit    uint16
+		// clocks 为通道关闭前的长度时钟数，0 表示不会关闭
+		clocks int
+	}{
+		{"ch1", []regWrite{{0x04000062, 60}, {0x04000063, 0xF0}, {0x04000065, 0xC0}}, 0x1, 4},
+		{"ch2 full length", []regWrite{{0x04000068, 0}, {0x04000069, 0xF0}, {0x0400006D, 0xC0}}, 0x2, 64},
+		{"ch3", []regWrite{{0x04000070, 0x80}, {0x04000072, 250}, {0x04000075, 0xC0}}, 0x4, 6},
+		{"ch4", []regWrite{{0x04000078, 63}, {0x04000079, 0xF0}, {0x0400007D, 0xC0}}, 0x8, 1},
+		{"length disabled", []regWrite{{0x04000062, 63}, {0x04000063, 0xF0}, {0x04000065, 0x80}}, 0x1, 0},
+	}
+
+	for _, tt := range tests {
+		a := newTestAPU()
+		a.apply(tt.writes)
+		if a.status()&tt.bit == 0 {
+			t.Fatalf("%s: channel not enabled by trigger", tt.name)
+		}
+		if tt.clocks == 0 {
+			a.ticks(200)
+			if a.status()&tt.bit == 0 {
+				t.Errorf("%s: channel stopped with length disabled", tt.name)
+			}
+			continue
+		}
+
+		// 第 n 个长度时钟在第 2n-1 次帧序列器步
+		a.ticks(2*tt.clocks - 2)
+		if a.status()&tt.bit == 0 {
+			t.Errorf("%s: channel stopped before %d length clocks", tt.name, tt.clocks)
+		}
+		a.ticks(1)
+		if a.status()&tt.bit != 0 {
+			t.Errorf("%s: channel still enabled after %d length clocks", tt.name, tt.clocks)
+		}
+	}
+}
+
+func TestEnvelopeStep(t *testing.T) {
+	tests := []struct {
+		name string
+		env  uint8
+		// 依次为第 1、2、3 次包络时钟后的音量
+		want [3]uint8
+	}{
+		{"decrease", 15<<4 | 1, [3]uint8{14, 13, 12}},
+		{"increase period 2", 0<<4 | 0x8 | 2, [3]uint8{0, 1, 1}},
+		{"saturate high", 15<<4 | 0x8 | 1, [3]uint8{15, 15, 15}},
+		{"saturate low", 1<<4 | 1, [3]uint8{0, 0, 0}},
+		{"period 0", 5 << 4, [3]uint8{5, 5, 5}},
+	}
+
+	for _, tt := range tests {
+		a := newTestAPU()
+		a.apply([]regWrite{{0x04000063, tt.env}, {0x04000065, 0x80}})
+		for i, want := range tt.want {
+			a.ticks(8)
+			if got := a.ch1.env.volume; got != want {
+				t.Errorf("%s: volume after %d envelope clocks = %d, want %d", tt.name, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestTriggerSideEffects(t *testing.T) {
+	t.Run("reloads expired length", func(t *testing.T) {
+		a := newTestAPU()
+		a.apply([]regWrite{{0x04000062, 63}, {0x04000063, 0xF0}, {0x04000065, 0xC0}})
+		a.ticks(1)
+		if a.status()&1 != 0 {
+			t.Fatal("channel still enabled after length expired")
+		}
+		// 计数为 0 时重新开始装入 64
+		a.WriteRegister8(0x04000065, 0xC0)
+		a.ticks(2*64 - 2)
+		if a.status()&1 == 0 {
+			t.Error("retriggered channel stopped before 64 length clocks")
+		}
+	})
+
+	t.Run("reloads envelope volume", func(t *testing.T) {
+		a := newTestAPU()
+		a.apply([]regWrite{{0x04000069, 15<<4 | 1}, {0x0400006D, 0x80}})
+		a.ticks(16)
+		if a.ch2.env.volume != 13 {
+			t.Fatalf("volume %d, want 13", a.ch2.env.volume)
+		}
+		a.WriteRegister8(0x0400006D, 0x80)
+		if a.ch2.env.volume != 15 {
+			t.Errorf("volume after trigger %d, want 15", a.ch2.env.volume)
+		}
+	})
+
+	t.Run("dac off", func(t *testing.T) {
+		a := newTestAPU()
+		// 初始音量 0 且减小时 DAC 关闭，重新开始不会打开通道
+		a.apply([]regWrite{{0x04000069, 0x00}, {0x0400006D, 0x80}})
+		if a.status()&2 != 0 {
+			t.Error("trigger enabled a channel with its DAC off")
+		}
+
+		// 工作中关闭 DAC 立即停止通道
+		a.apply([]regWrite{{0x04000069, 0xF0}, {0x0400006D, 0x80}})
+		a.WriteRegister8(0x04000069, 0x00)
+		if a.status()&2 != 0 {
+			t.Error("channel still enabled after its DAC was turned off")
+		}
+	})
+
+	t.Run("master disable", func(t *testing.T) {
+		a := newTestAPU()
+		a.apply([]regWrite{{0x04000063, 0xF0}, {0x04000065, 0x80}})
+		a.WriteRegister8(0x04000084, 0)
+		if a.status() != 0 || a.SOUND1CNT_H != 0 {
+			t.Error("master disable did not clear the channels")
+		}
+		// 总开关关闭时写入被忽略
+		a.WriteRegister8(0x04000063, 0xF0)
+		if a.SOUND1CNT_H != 0 {
+			t.Errorf("SOUND1CNT_H = %04X after write with master off", a.SOUND1CNT_H)
+		}
+	})
+}
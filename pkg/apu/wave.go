@@ -0,0 +1,137 @@
+package apu
+
+// waveChannel 是通道 3，播放波形 RAM 中的 4 位样本
+// 波形 RAM 分两组，每组 16 字节 32 个样本，高 4 位先播放
+type waveChannel struct {
+	enabled bool
+	dac     bool
+	freq    uint16
+	timer   int
+	pos     int
+
+	// dimension 为 true 时两组连成 64 个样本，bank 为正在播放（或最先播放）的组
+	dimension bool
+	bank      int
+
+	volume  uint8
+	force75 bool
+	length  lengthCounter
+	ram     *[2][16]byte
+	sample  uint8
+}
+
+func newWaveChannel(ram *[2][16]byte) *waveChannel {
+	return &waveChannel{ram: ram, length: lengthCounter{max: 256}}
+}
+
+// period 返回每个样本的 CPU 周期数，样本频率为 2097152/(2048-n) Hz
+func (c *waveChannel) period() int {
+	return (2048 - int(c.freq)) * 8
+}
+
+// writeControl 处理 SOUND3CNT_L：bit5 64 样本模式，bit6 播放组，bit7 通道开关
+func (c *waveChannel) writeControl(val uint8) {
+	c.dimension = val&0x20 != 0
+	c.bank = int(val>>6) & 1
+	c.dac = val&0x80 != 0
+	if !c.dac {
+		c.enabled = false
+	}
+}
+
+func (c *waveChannel) writeLength(val uint8) {
+	c.length.load(int(val))
+}
+
+// writeVolume 处理 SOUND3CNT_H 高字节：bit5-6 音量（0/100/50/25%），bit7 强制 75%
+func (c *waveChannel) writeVolume(val uint8) {
+	c.volume = (val >> 5) & 0x3
+	c.force75 = val&0x80 != 0
+}
+
+func (c *waveChannel) writeFreqLow(val uint8) {
+	c.freq = c.freq&0x700 | uint16(val)
+}
+
+// writeFreqHigh 处理 SOUND3CNT_X 高字节：bit0-2 频率高位，bit6 长度使能，bit7 重新开始
+func (c *waveChannel) writeFreqHigh(val uint8) {
+	c.freq = c.freq&0xFF | uint16(val&0x7)<<8
+	c.length.enabled = val&0x40 != 0
+	if val&0x80 != 0 {
+		c.trigger()
+	}
+}
+
+func (c *waveChannel) trigger() {
+	c.enabled = c.dac
+	c.length.trigger()
+	c.timer = c.period()
+	c.pos = 0
+	c.sample = c.fetch()
+}
+
+func (c *waveChannel) clockLength() {
+	if c.length.clock() {
+		c.enabled = false
+	}
+}
+
+// fetch 取出当前位置的样本，64 样本模式下先播放 bank 组再播放另一组
+func (c *waveChannel) fetch() uint8 {
+	bank := c.bank
+	pos := c.pos
+	if pos >= 32 {
+		bank ^= 1
+		pos -= 32
+	}
+
+	b := c.ram[bank][pos/2]
+	if pos&1 == 0 {
+		return b >> 4
+	}
+	return b & 0xF
+}
+
+func (c *waveChannel) step(cycles int) {
+	if !c.enabled {
+		return
+	}
+
+	samples := 32
+	if c.dimension {
+		samples = 64
+	}
+
+	c.timer -= cycles
+	for c.timer <= 0 {
+		c.timer += c.period()
+		c.pos = (c.pos + 1) % samples
+		c.sample = c.fetch()
+	}
+}
+
+// output 返回 -15..15 的有符号输出
+func (c *waveChannel) output() int {
+	if !c.enabled {
+		return 0
+	}
+
+	out := int(c.sample)*2 - 15
+	switch {
+	case c.force75:
+		return out * 3 / 4
+	case c.volume == 0:
+		return 0
+	case c.volume == 1:
+		return out
+	case c.volume == 2:
+		return out / 2
+	default:
+		return out / 4
+	}
+}
+
+// cpuBank 返回 CPU 可以访问的波形 RAM 组，即不在播放的那一组
+func (c *waveChannel) cpuBank() int {
+	return c.bank ^ 1
+}
@@ -0,0 +1,111 @@
+package apu
+
+import "testing"
+
+// fillWave 通过 CPU 把 32 个样本写入当前可访问的波形 RAM 组
+func (a *APU) fillWave(sample func(i int) uint8) {
+	for i := 0; i < 16; i++ {
+		a.WriteRegister8(0x04000090+uint32(i), sample(2*i)<<4|sample(2*i+1))
+	}
+}
+
+// playWave 以每样本 8 周期的最高频率重新开始通道 3，返回之后 n 个样本
+func (a *APU) playWave(control uint8, n int) []uint8 {
+	a.apply([]regWrite{
+		{0x04000070, control | 0x80},
+		{0x04000073, 0x20},
+		{0x04000074, 0xFF},
+		{0x04000075, 0x87},
+	})
+	out := []uint8{a.ch3.sample}
+	for len(out) < n {
+		a.Step(8)
+		out = append(out, a.ch3.sample)
+	}
+	return out
+}
+
+func rampUp(i int) uint8   { return uint8(i & 0xF) }
+func rampDown(i int) uint8 { return uint8(15 - i&0xF) }
+
+func expectSamples(t *testing.T, name string, got []uint8, want func(i int) uint8) {
+	t.Helper()
+	for i, s := range got {
+		if s != want(i) {
+			t.Fatalf("%s: sample %d = %d, want %d", name, i, s, want(i))
+		}
+	}
+}
+
+func TestWaveBankSelect(t *testing.T) {
+	a := newTestAPU()
+	// 播放组 0 时 CPU 写入组 1，反之亦然
+	a.WriteRegister8(0x04000070, 0x00)
+	a.fillWave(rampUp)
+	a.WriteRegister8(0x04000070, 0x40)
+	a.fillWave(rampDown)
+
+	if a.WAVE_RAM[1][0] != 0x01 || a.WAVE_RAM[0][0] != 0xFE {
+		t.Fatalf("wave RAM banks %02X %02X, want FE 01", a.WAVE_RAM[0][0], a.WAVE_RAM[1][0])
+	}
+
+	expectSamples(t, "bank 0", a.playWave(0x00, 64), func(i int) uint8 { return rampDown(i % 32) })
+	expectSamples(t, "bank 1", a.playWave(0x40, 64), func(i int) uint8 { return rampUp(i % 32) })
+}
+
+func TestWaveTwoBankMode(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		control uint8
+		first   func(i int) uint8
+		second  func(i int) uint8
+	}{
+		{"bank 0 first", 0x20, rampDown, rampUp},
+		{"bank 1 first", 0x20 | 0x40, rampUp, rampDown},
+	} {
+		a := newTestAPU()
+		a.WriteRegister8(0x04000070, 0x00)
+		a.fillWave(rampUp)
+		a.WriteRegister8(0x04000070, 0x40)
+		a.fillWave(rampDown)
+
+		// 64 个样本播完后回到起始组
+		expectSamples(t, tt.name, a.playWave(tt.control, 128), func(i int) uint8 {
+			if i%64 < 32 {
+				return tt.first(i % 32)
+			}
+			return tt.second(i % 32)
+		})
+	}
+}
+
+func TestWaveCPUAccessesIdleBank(t *testing.T) {
+	a := newTestAPU()
+	a.WriteRegister8(0x04000070, 0x40)
+	a.fillWave(rampUp)
+	a.WriteRegister8(0x04000070, 0x00)
+	a.fillWave(rampDown)
+
+	// 播放组 0 时读写的是组 1，不影响正在播放的样本
+	a.playWave(0x00, 4)
+	if got := a.ReadRegister(0x04000090); got != 0xDCFE {
+		t.Errorf("CPU reads %04X while bank 0 plays, want DCFE", got)
+	}
+	a.WriteRegister(0x04000090, 0x0000)
+	if a.WAVE_RAM[0][0] != 0x01 || a.WAVE_RAM[1][0] != 0x00 {
+		t.Errorf("CPU write reached the playing bank")
+	}
+	// 播放一整圈回到第 3 个样本
+	for i := 0; i < 32; i++ {
+		a.Step(8)
+	}
+	if a.ch3.sample != rampUp(3) {
+		t.Errorf("playing sample %d after CPU write, want %d", a.ch3.sample, rampUp(3))
+	}
+
+	// 切换播放组后 CPU 看到另一组
+	a.WriteRegister8(0x04000070, 0x80|0x40)
+	if got := a.ReadRegister(0x04000090); got != 0x2301 {
+		t.Errorf("CPU reads %04X while bank 1 plays, want 2301", got)
+	}
+}
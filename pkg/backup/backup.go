@@ -0,0 +1,58 @@
+package backup
+
+// Device 是映射在 0x0E000000 区域的卡带存档芯片
+// addr 为相对 0x0E000000 的偏移，镜像由设备自己处理
+type Device interface {
+	Read8(addr uint32) uint8
+	Write8(addr uint32, val uint8)
+
+	Load(data []byte)
+	Data() []byte
+
+	Dirty() bool
+	ClearDirty()
+}
+
+const SRAMSize = 0x8000
+
+type SRAM struct {
+	data  []byte
+	dirty bool
+}
+
+func NewSRAM() *SRAM {
+	return &SRAM{
+		data: make([]byte, SRAMSize),
+	}
+}
+
+func (s *SRAM) Read8(addr uint32) uint8 {
+	return s.data[addr&(SRAMSize-1)]
+}
+
+func (s *SRAM) Write8(addr uint32, val uint8) {
+	offset := addr & (SRAMSize - 1)
+	if s.data[offset] != val {
+		s.data[offset] = val
+		s.dirty = true
+	}
+}
+
+func (s *SRAM) Load(data []byte) {
+	copy(s.data, data)
+	s.dirty = false
+}
+
+func (s *SRAM) Data() []byte {
+	data := make([]byte, len(s.data))
+	copy(data, s.data)
+	return data
+}
+
+func (s *SRAM) Dirty() bool {
+	return s.dirty
+}
+
+func (s *SRAM) ClearDirty() {
+	s.dirty = false
+}
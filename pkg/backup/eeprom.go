@@ -0,0 +1,199 @@
+package backup
+
+const (
+	EEPROMSize512 = 0x200
+	EEPROMSize8K  = 0x2000
+
+	// 读请求: 2 位命令 + 地址 + 1 位结束位
+	// 写请求: 2 位命令 + 地址 + 64 位数据 + 1 位结束位
+	eepromReadLength6   = 9
+	eepromReadLength14  = 17
+	eepromWriteLength6  = 73
+	eepromWriteLength14 = 81
+
+	eepromReadBits = 68
+)
+
+const (
+	eepromIdle = iota
+	eepromCommand
+	eepromAddress
+	eepromWriteData
+	eepromStop
+	eepromReadData
+)
+
+// EEPROM 通过 0x0D000000 区域逐位串行访问，每次 16 位读写只有 bit 0 有效
+type EEPROM struct {
+	data     []byte
+	size     int
+	detected bool
+	dirty    bool
+
+	state     int
+	reading   bool
+	bitCount  int
+	address   uint32
+	buffer    uint64
+	readCount int
+}
+
+// NewEEPROM 创建 EEPROM，size 为 0 时根据第一次 DMA 的长度识别容量
+func NewEEPROM(size int) *EEPROM {
+	e := &EEPROM{
+		data: make([]byte, EEPROMSize8K),
+		size: EEPROMSize512,
+	}
+	if size == EEPROMSize512 || size == EEPROMSize8K {
+		e.size = size
+		e.detected = true
+	}
+	for i := range e.data {
+		e.data[i] = 0xFF
+	}
+	return e
+}
+
+func (e *EEPROM) Size() int {
+	return e.size
+}
+
+func (e *EEPROM) addressBits() int {
+	if e.size == EEPROMSize8K {
+		return 14
+	}
+	return 6
+}
+
+// DetectSize 根据写入 EEPROM 的 DMA 长度判断地址宽度
+func (e *EEPROM) DetectSize(dmaLength int) {
+	if e.detected {
+		return
+	}
+
+	switch dmaLength {
+	case eepromReadLength6, eepromWriteLength6:
+		e.size = EEPROMSize512
+		e.detected = true
+	case eepromReadLength14, eepromWriteLength14:
+		e.size = EEPROMSize8K
+		e.detected = true
+	}
+}
+
+func (e *EEPROM) Read16() uint16 {
+	if e.state != eepromReadData {
+		// 空闲或写入完成后返回 1 表示就绪
+		return 1
+	}
+
+	e.readCount--
+	if e.readCount == 0 {
+		e.state = eepromIdle
+	}
+
+	// 前 4 位是无效位
+	if e.readCount >= 64 {
+		return 0
+	}
+
+	return uint16(e.buffer>>uint(e.readCount)) & 1
+}
+
+func (e *EEPROM) Write16(val uint16) {
+	bit := uint64(val & 1)
+
+	switch e.state {
+	case eepromIdle, eepromReadData:
+		if bit == 1 {
+			e.state = eepromCommand
+		}
+	case eepromCommand:
+		e.reading = bit == 1
+		e.state = eepromAddress
+		e.bitCount = 0
+		e.address = 0
+	case eepromAddress:
+		e.address = e.address<<1 | uint32(bit)
+		e.bitCount++
+		if e.bitCount == e.addressBits() {
+			e.bitCount = 0
+			e.buffer = 0
+			if e.reading {
+				e.state = eepromStop
+			} else {
+				e.state = eepromWriteData
+			}
+		}
+	case eepromWriteData:
+		e.buffer = e.buffer<<1 | bit
+		e.bitCount++
+		if e.bitCount == 64 {
+			e.state = eepromStop
+		}
+	case eepromStop:
+		if e.reading {
+			e.buffer = e.readBlock()
+			e.readCount = eepromReadBits
+			e.state = eepromReadData
+		} else {
+			e.writeBlock(e.buffer)
+			e.state = eepromIdle
+		}
+	}
+}
+
+func (e *EEPROM) blockOffset() uint32 {
+	blocks := uint32(e.size / 8)
+	return (e.address & (blocks - 1)) * 8
+}
+
+func (e *EEPROM) readBlock() uint64 {
+	offset := e.blockOffset()
+	var block uint64
+	for i := uint32(0); i < 8; i++ {
+		block = block<<8 | uint64(e.data[offset+i])
+	}
+	return block
+}
+
+func (e *EEPROM) writeBlock(block uint64) {
+	offset := e.blockOffset()
+	for i := uint32(0); i < 8; i++ {
+		e.data[offset+i] = uint8(block >> (56 - i*8))
+	}
+	e.dirty = true
+}
+
+// EEPROM 卡带不在 0x0E000000 区域挂接任何芯片
+func (e *EEPROM) Read8(addr uint32) uint8 {
+	return 0xFF
+}
+
+func (e *EEPROM) Write8(addr uint32, val uint8) {
+}
+
+func (e *EEPROM) Load(data []byte) {
+	if len(data) > EEPROMSize512 {
+		e.size = EEPROMSize8K
+	} else {
+		e.size = EEPROMSize512
+	}
+	e.detected = true
+	copy(e.data, data)
+	e.dirty = false
+}
+
+func (e *EEPROM) Data() []byte {
+	data := make([]byte, e.size)
+	copy(data, e.data)
+	return data
+}
+
+func (e *EEPROM) Dirty() bool {
+	return e.dirty
+}
+
+func (e *EEPROM) ClearDirty() {
+	e.dirty = false
+}
@@ -0,0 +1,139 @@
+package backup_test
+
+import (
+	"testing"
+
+	"gba/pkg/backup"
+	"gba/pkg/dma"
+	"gba/pkg/mmu"
+)
+
+const (
+	eepromAddr = 0x0D000000
+	bitsAddr   = 0x02000000
+	readAddr   = 0x02001000
+)
+
+// eepromBus 像游戏一样把请求位串放进 WRAM，再用 DMA3 搬到 EEPROM 区域
+type eepromBus struct {
+	t      *testing.T
+	mmu    *mmu.MMU
+	dma    *dma.DMA
+	eeprom *backup.EEPROM
+}
+
+func newEEPROMBus(t *testing.T, size int) *eepromBus {
+	m := mmu.New()
+	d := dma.New(m.Read32, m.Write32, m.Read16, m.Write16, func(uint16) {})
+	m.DMA = d
+
+	e := backup.NewEEPROM(size)
+	m.SetBackup(e)
+	return &eepromBus{t: t, mmu: m, dma: d, eeprom: e}
+}
+
+// dma3 启动一次 16 位、立即开始、地址递增的 DMA3 传输并等待完成
+func (b *eepromBus) dma3(src, dst uint32, count int) {
+	b.mmu.Write32(0x040000D4, src)
+	b.mmu.Write32(0x040000D8, dst)
+	b.mmu.Write16(0x040000DC, uint16(count))
+	b.mmu.Write16(0x040000DE, 0x8000)
+	for b.dma.IsActive(3) {
+		b.dma.Step()
+	}
+}
+
+func (b *eepromBus) send(bits []uint16) {
+	for i, bit := range bits {
+		b.mmu.Write16(bitsAddr+uint32(i)*2, bit)
+	}
+	b.dma3(bitsAddr, eepromAddr, len(bits))
+}
+
+// appendBits 把 val 的低 n 位按高位在前追加到请求位串
+func appendBits(bits []uint16, val uint64, n int) []uint16 {
+	for i := n - 1; i >= 0; i-- {
+		bits = append(bits, uint16(val>>uint(i))&1)
+	}
+	return bits
+}
+
+// readRequest 生成读请求：11 + 地址 + 结束位 0
+func readRequest(addrBits int, addr uint32) []uint16 {
+	bits := []uint16{1, 1}
+	bits = appendBits(bits, uint64(addr), addrBits)
+	return append(bits, 0)
+}
+
+// writeRequest 生成写请求：10 + 地址 + 64 位数据 + 结束位 0
+func writeRequest(addrBits int, addr uint32, data uint64) []uint16 {
+	bits := []uint16{1, 0}
+	bits = appendBits(bits, uint64(addr), addrBits)
+	bits = appendBits(bits, data, 64)
+	return append(bits, 0)
+}
+
+// read64 用 68 次 DMA 读出一个块，跳过前 4 个无效位
+func (b *eepromBus) read64() uint64 {
+	b.dma3(eepromAddr, readAddr, 68)
+
+	var val uint64
+	for i := 4; i < 68; i++ {
+		val = val<<1 | uint64(b.mmu.Read16(readAddr+uint32(i)*2)&1)
+	}
+	return val
+}
+
+func TestEEPROMRequestLengths(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    int
+		addrBits int
+		wantSize int
+	}{
+		{"9-bit read", 9, 6, backup.EEPROMSize512},
+		{"17-bit read", 17, 14, backup.EEPROMSize8K},
+		{"73-bit write", 73, 6, backup.EEPROMSize512},
+		{"81-bit write", 81, 14, backup.EEPROMSize8K},
+	}
+
+	const data = 0x0123456789ABCDEF
+
+	for _, tt := range tests {
+		bus := newEEPROMBus(t, 0)
+		addr := uint32(0x3F)
+		if tt.addrBits == 14 {
+			addr = 0x3FF
+		}
+
+		// 第一次 DMA 的长度决定地址宽度
+		if tt.first == 9 || tt.first == 17 {
+			bus.send(readRequest(tt.addrBits, addr))
+			if got := bus.read64(); got != ^uint64(0) {
+				t.Errorf("%s: blank block = %016X, want all ones", tt.name, got)
+			}
+		} else {
+			bus.send(writeRequest(tt.addrBits, addr, data))
+		}
+		if got := bus.eeprom.Size(); got != tt.wantSize {
+			t.Errorf("%s: size = %d, want %d", tt.name, got, tt.wantSize)
+			continue
+		}
+
+		// 写入完成后芯片返回 1 表示就绪
+		bus.send(writeRequest(tt.addrBits, addr, data))
+		if got := bus.mmu.Read16(eepromAddr) & 1; got != 1 {
+			t.Errorf("%s: ready bit = %d, want 1", tt.name, got)
+		}
+
+		bus.send(readRequest(tt.addrBits, addr))
+		if got := bus.read64(); got != data {
+			t.Errorf("%s: read back %016X, want %016X", tt.name, got, uint64(data))
+		}
+
+		saved := bus.eeprom.Data()
+		if len(saved) != tt.wantSize || saved[addr*8] != 0x01 || saved[addr*8+7] != 0xEF {
+			t.Errorf("%s: save data block %d = % X", tt.name, addr, saved[addr*8:addr*8+8])
+		}
+	}
+}
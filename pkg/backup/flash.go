@@ -0,0 +1,216 @@
+package backup
+
+const (
+	FlashSize64K  = 0x10000
+	FlashSize128K = 0x20000
+
+	flashBankSize   = 0x10000
+	flashSectorSize = 0x1000
+	atmelPageSize   = 128
+)
+
+// FlashID 是芯片 ID 模式下 0x0E000000/0x0E000001 返回的厂商码和器件码
+type FlashID struct {
+	Maker  uint8
+	Device uint8
+}
+
+var (
+	FlashPanasonic64K = FlashID{Maker: 0x32, Device: 0x1B}
+	FlashSST64K       = FlashID{Maker: 0xBF, Device: 0xD4}
+	FlashMacronix64K  = FlashID{Maker: 0xC2, Device: 0x1C}
+	FlashAtmel64K     = FlashID{Maker: 0x1F, Device: 0x3D}
+	FlashMacronix128K = FlashID{Maker: 0xC2, Device: 0x09}
+	FlashSanyo128K    = FlashID{Maker: 0x62, Device: 0x13}
+)
+
+const (
+	flashReady = iota
+	flashUnlock1
+	flashUnlock2
+	flashProgram
+	flashBankSwitch
+)
+
+type Flash struct {
+	// ID 可以在加载 ROM 后修改，用于通过游戏的芯片检测
+	ID FlashID
+
+	data  []byte
+	bank  uint32
+	dirty bool
+
+	state       int
+	idMode      bool
+	eraseArmed  bool
+	pageAddr    uint32
+	pageRemains int
+}
+
+func NewFlash(size int) *Flash {
+	f := &Flash{
+		data: make([]byte, size),
+	}
+
+	if size == FlashSize128K {
+		f.ID = FlashSanyo128K
+	} else {
+		f.ID = FlashPanasonic64K
+	}
+
+	for i := range f.data {
+		f.data[i] = 0xFF
+	}
+	return f
+}
+
+func (f *Flash) Size() int {
+	return len(f.data)
+}
+
+func (f *Flash) Read8(addr uint32) uint8 {
+	offset := addr & (flashBankSize - 1)
+
+	if f.idMode {
+		switch offset {
+		case 0x0000:
+			return f.ID.Maker
+		case 0x0001:
+			return f.ID.Device
+		}
+	}
+
+	return f.data[f.bank*flashBankSize+offset]
+}
+
+func (f *Flash) Write8(addr uint32, val uint8) {
+	offset := addr & (flashBankSize - 1)
+
+	switch f.state {
+	case flashProgram:
+		f.program(offset, val)
+		return
+	case flashBankSwitch:
+		if offset == 0x0000 && len(f.data) == FlashSize128K {
+			f.bank = uint32(val & 1)
+		}
+		f.state = flashReady
+		return
+	case flashReady:
+		if offset == 0x5555 && val == 0xAA {
+			f.state = flashUnlock1
+		} else if val == 0xF0 {
+			// 部分芯片允许不带解锁序列直接复位
+			f.idMode = false
+		}
+		return
+	case flashUnlock1:
+		if offset == 0x2AAA && val == 0x55 {
+			f.state = flashUnlock2
+		} else {
+			f.state = flashReady
+		}
+		return
+	}
+
+	// 已收到 0x5555=AA, 0x2AAA=55，这里处理命令字节
+	f.state = flashReady
+
+	if f.eraseArmed {
+		f.eraseArmed = false
+		switch {
+		case offset == 0x5555 && val == 0x10:
+			f.eraseChip()
+		case val == 0x30:
+			f.eraseSector(offset)
+		}
+		// 其他字节不是擦除命令，真实芯片忽略并回到就绪状态
+		return
+	}
+
+	if offset != 0x5555 {
+		return
+	}
+
+	switch val {
+	case 0x90:
+		f.idMode = true
+	case 0xF0:
+		f.idMode = false
+	case 0x80:
+		f.eraseArmed = true
+	case 0xA0:
+		f.state = flashProgram
+		f.pageRemains = 0
+	case 0xB0:
+		if len(f.data) == FlashSize128K {
+			f.state = flashBankSwitch
+		}
+	}
+}
+
+func (f *Flash) program(offset uint32, val uint8) {
+	addr := f.bank*flashBankSize + offset
+
+	// Atmel 芯片没有扇区擦除，按 128 字节页写入，写入前自动擦除整页
+	if f.ID == FlashAtmel64K {
+		if f.pageRemains == 0 {
+			f.pageAddr = addr &^ (atmelPageSize - 1)
+			f.pageRemains = atmelPageSize
+			for i := uint32(0); i < atmelPageSize; i++ {
+				f.data[f.pageAddr+i] = 0xFF
+			}
+		}
+		f.data[f.pageAddr|(addr&(atmelPageSize-1))] = val
+		f.dirty = true
+		f.pageRemains--
+		if f.pageRemains == 0 {
+			f.state = flashReady
+		}
+		return
+	}
+
+	f.data[addr] = val
+	f.dirty = true
+	f.state = flashReady
+}
+
+func (f *Flash) eraseChip() {
+	for i := range f.data {
+		f.data[i] = 0xFF
+	}
+	f.dirty = true
+}
+
+func (f *Flash) eraseSector(offset uint32) {
+	start := f.bank*flashBankSize + offset&^(flashSectorSize-1)
+	for i := start; i < start+flashSectorSize; i++ {
+		f.data[i] = 0xFF
+	}
+	f.dirty = true
+}
+
+func (f *Flash) Load(data []byte) {
+	// 64K 检测错误时按存档大小升级为 128K
+	if len(data) == FlashSize128K && len(f.data) == FlashSize64K {
+		f.data = make([]byte, FlashSize128K)
+		f.ID = FlashSanyo128K
+	}
+	copy(f.data, data)
+	f.bank = 0
+	f.dirty = false
+}
+
+func (f *Flash) Data() []byte {
+	data := make([]byte, len(f.data))
+	copy(data, f.data)
+	return data
+}
+
+func (f *Flash) Dirty() bool {
+	return f.dirty
+}
+
+func (f *Flash) ClearDirty() {
+	f.dirty = false
+}
@@ -0,0 +1,164 @@
+package backup
+
+import "testing"
+
+// command 按游戏的方式发送 0x5555=AA, 0x2AAA=55 解锁序列和命令字节
+func command(f *Flash, cmd uint8) {
+	f.Write8(0x5555, 0xAA)
+	f.Write8(0x2AAA, 0x55)
+	f.Write8(0x5555, cmd)
+}
+
+func program(f *Flash, addr uint32, val uint8) {
+	command(f, 0xA0)
+	f.Write8(addr, val)
+}
+
+func TestFlashIDMode(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		id   FlashID
+		want FlashID
+	}{
+		{"64K default", FlashSize64K, FlashID{}, FlashPanasonic64K},
+		{"128K default", FlashSize128K, FlashID{}, FlashSanyo128K},
+		{"128K Macronix", FlashSize128K, FlashMacronix128K, FlashMacronix128K},
+	}
+
+	for _, tt := range tests {
+		f := NewFlash(tt.size)
+		if tt.id != (FlashID{}) {
+			f.ID = tt.id
+		}
+
+		command(f, 0x90)
+		got := FlashID{Maker: f.Read8(0x0000), Device: f.Read8(0x0001)}
+		if got != tt.want {
+			t.Errorf("%s: ID = %02X/%02X, want %02X/%02X", tt.name, got.Maker, got.Device, tt.want.Maker, tt.want.Device)
+		}
+
+		command(f, 0xF0)
+		if got := f.Read8(0x0000); got != 0xFF {
+			t.Errorf("%s: after exit, [0000] = %02X, want FF", tt.name, got)
+		}
+	}
+}
+
+func TestFlashUnlockSequence(t *testing.T) {
+	f := NewFlash(FlashSize64K)
+
+	// 不完整或地址错误的解锁序列不能进入命令状态
+	f.Write8(0x5555, 0xAA)
+	f.Write8(0x2AAB, 0x55)
+	f.Write8(0x5555, 0xA0)
+	f.Write8(0x0100, 0x12)
+	if got := f.Read8(0x0100); got != 0xFF {
+		t.Errorf("write without unlock: [0100] = %02X, want FF", got)
+	}
+	if f.Dirty() {
+		t.Error("write without unlock marked the chip dirty")
+	}
+
+	program(f, 0x0100, 0x12)
+	if got := f.Read8(0x0100); got != 0x12 {
+		t.Errorf("after program: [0100] = %02X, want 12", got)
+	}
+	if !f.Dirty() {
+		t.Error("program did not mark the chip dirty")
+	}
+
+	// 编程后回到就绪状态，下一次写入不会被当作数据
+	f.Write8(0x0101, 0x34)
+	if got := f.Read8(0x0101); got != 0xFF {
+		t.Errorf("second write without command: [0101] = %02X, want FF", got)
+	}
+}
+
+func TestFlashErase(t *testing.T) {
+	f := NewFlash(FlashSize64K)
+	program(f, 0x0000, 0x11)
+	program(f, 0x0FFF, 0x22)
+	program(f, 0x1000, 0x33)
+
+	// 扇区擦除：0x80 之后再解锁一次，在扇区内任意地址写 0x30
+	command(f, 0x80)
+	f.Write8(0x5555, 0xAA)
+	f.Write8(0x2AAA, 0x55)
+	f.Write8(0x0800, 0x30)
+
+	for _, c := range []struct {
+		addr uint32
+		want uint8
+	}{{0x0000, 0xFF}, {0x0FFF, 0xFF}, {0x1000, 0x33}} {
+		if got := f.Read8(c.addr); got != c.want {
+			t.Errorf("after sector erase: [%04X] = %02X, want %02X", c.addr, got, c.want)
+		}
+	}
+
+	// 整片擦除
+	command(f, 0x80)
+	command(f, 0x10)
+	if got := f.Read8(0x1000); got != 0xFF {
+		t.Errorf("after chip erase: [1000] = %02X, want FF", got)
+	}
+}
+
+func TestFlashAtmelPageProgram(t *testing.T) {
+	f := NewFlash(FlashSize64K)
+	f.ID = FlashAtmel64K
+	old := make([]byte, FlashSize64K)
+	for i := range old {
+		old[i] = 0x55
+	}
+	f.Load(old)
+
+	// 一条 0xA0 命令之后连续写满 128 字节的一页
+	command(f, 0xA0)
+	for i := uint32(0); i < atmelPageSize; i++ {
+		f.Write8(0x0100+i, uint8(i))
+	}
+	for i := uint32(0); i < atmelPageSize; i++ {
+		if got := f.Read8(0x0100 + i); got != uint8(i) {
+			t.Fatalf("[%04X] = %02X, want %02X", 0x0100+i, got, uint8(i))
+		}
+	}
+
+	// 页写完后回到就绪状态，相邻的页不受影响
+	f.Write8(0x0200, 0x77)
+	if got := f.Read8(0x0200); got != 0x55 {
+		t.Errorf("write after page: [0200] = %02X, want 55", got)
+	}
+}
+
+func TestFlashBankSwitch(t *testing.T) {
+	f := NewFlash(FlashSize128K)
+	program(f, 0x1234, 0xAA)
+
+	command(f, 0xB0)
+	f.Write8(0x0000, 1)
+	if got := f.Read8(0x1234); got != 0xFF {
+		t.Errorf("bank 1 before program: [1234] = %02X, want FF", got)
+	}
+	program(f, 0x1234, 0xBB)
+
+	command(f, 0xB0)
+	f.Write8(0x0000, 0)
+	if got := f.Read8(0x1234); got != 0xAA {
+		t.Errorf("bank 0: [1234] = %02X, want AA", got)
+	}
+
+	data := f.Data()
+	if data[0x1234] != 0xAA || data[FlashSize64K+0x1234] != 0xBB {
+		t.Errorf("save data = %02X/%02X, want AA/BB", data[0x1234], data[FlashSize64K+0x1234])
+	}
+
+	// 64K 芯片没有 bank 切换命令
+	small := NewFlash(FlashSize64K)
+	command(small, 0xB0)
+	small.Write8(0x0000, 1)
+	program(small, 0x0000, 0x12)
+	if got := small.Read8(0x0000); got != 0x12 {
+		t.Errorf("64K after bank command: [0000] = %02X, want 12", got)
+	}
+}
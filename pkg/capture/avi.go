@@ -0,0 +1,281 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// AVI 1.0 (RIFF) 单个文件不能超过 2GB，更长的录像请用 Y4M
+const aviMaxSize = 0x7F000000
+
+var ErrAVITooLarge = errors.New("AVI file reached the 2GB limit")
+
+// AVIWriter 写出未压缩的 AVI：24 位 RGB 视频流和 16 位立体声 PCM 音频流
+type AVIWriter struct {
+	f      *os.File
+	w      *bufio.Writer
+	width  int
+	height int
+
+	pos       int64
+	moviStart int64
+	index     []aviIndexEntry
+	frame     []byte
+
+	frames       uint32
+	audioSamples uint32
+
+	// 需要在 Close 时回填的字段位置
+	riffSizeAt    int64
+	totalFramesAt int64
+	videoLengthAt int64
+	audioLengthAt int64
+	moviSizeAt    int64
+}
+
+type aviIndexEntry struct {
+	id     string
+	offset uint32
+	size   uint32
+}
+
+func NewAVIWriter(path string, width, height, sampleRate int) (*AVIWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	a := &AVIWriter{
+		f:      f,
+		w:      bufio.NewWriter(f),
+		width:  width,
+		height: height,
+		frame:  make([]byte, width*height*3),
+	}
+
+	header := a.buildHeader(sampleRate)
+	if _, err := a.w.Write(header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write AVI header: %w", err)
+	}
+	a.pos = int64(len(header))
+	return a, nil
+}
+
+func (a *AVIWriter) buildHeader(sampleRate int) []byte {
+	var b bytes.Buffer
+	le := binary.LittleEndian
+	u32 := func(v uint32) { binary.Write(&b, le, v) }
+	u16 := func(v uint16) { binary.Write(&b, le, v) }
+	mark := func() int64 { return int64(b.Len()) }
+
+	frameBytes := uint32(a.width * a.height * 3)
+
+	b.WriteString("RIFF")
+	a.riffSizeAt = mark()
+	u32(0)
+	b.WriteString("AVI ")
+
+	b.WriteString("LIST")
+	hdrlSizeAt := mark()
+	u32(0)
+	b.WriteString("hdrl")
+
+	b.WriteString("avih")
+	u32(56)
+	u32(uint32(1000000 * FrameRateDen / FrameRateNum))
+	u32(frameBytes * 60)
+	u32(0)
+	u32(0x10) // AVIF_HASINDEX
+	a.totalFramesAt = mark()
+	u32(0)
+	u32(0)
+	u32(2)
+	u32(frameBytes)
+	u32(uint32(a.width))
+	u32(uint32(a.height))
+	b.Write(make([]byte, 16))
+
+	// 视频流
+	b.WriteString("LIST")
+	u32(4 + 8 + 56 + 8 + 40)
+	b.WriteString("strl")
+	b.WriteString("strh")
+	u32(56)
+	b.WriteString("vids")
+	b.WriteString("DIB ")
+	u32(0)
+	u16(0)
+	u16(0)
+	u32(0)
+	u32(FrameRateDen)
+	u32(FrameRateNum)
+	u32(0)
+	a.videoLengthAt = mark()
+	u32(0)
+	u32(frameBytes)
+	u32(0xFFFFFFFF)
+	u32(0)
+	u16(0)
+	u16(0)
+	u16(uint16(a.width))
+	u16(uint16(a.height))
+	b.WriteString("strf")
+	u32(40)
+	u32(40)
+	u32(uint32(a.width))
+	u32(uint32(a.height)) // 正数表示自下而上存储
+	u16(1)
+	u16(24)
+	u32(0) // BI_RGB
+	u32(frameBytes)
+	b.Write(make([]byte, 16))
+
+	// 音频流
+	b.WriteString("LIST")
+	u32(4 + 8 + 56 + 8 + 18)
+	b.WriteString("strl")
+	b.WriteString("strh")
+	u32(56)
+	b.WriteString("auds")
+	u32(0)
+	u32(0)
+	u16(0)
+	u16(0)
+	u32(0)
+	u32(1)
+	u32(uint32(sampleRate))
+	u32(0)
+	a.audioLengthAt = mark()
+	u32(0)
+	u32(uint32(sampleRate * 4))
+	u32(0xFFFFFFFF)
+	u32(4)
+	b.Write(make([]byte, 8))
+	b.WriteString("strf")
+	u32(18)
+	u16(1) // PCM
+	u16(2)
+	u32(uint32(sampleRate))
+	u32(uint32(sampleRate * 4))
+	u16(4)
+	u16(16)
+	u16(0)
+
+	le.PutUint32(b.Bytes()[hdrlSizeAt:], uint32(mark()-hdrlSizeAt-4))
+
+	b.WriteString("LIST")
+	a.moviSizeAt = mark()
+	u32(0)
+	a.moviStart = mark()
+	b.WriteString("movi")
+
+	return b.Bytes()
+}
+
+func (a *AVIWriter) writeChunk(id string, data []byte) error {
+	size := int64(len(data))
+	padded := size + size&1
+	if a.pos+8+padded+int64(len(a.index)+1)*16 > aviMaxSize {
+		return ErrAVITooLarge
+	}
+
+	var hdr [8]byte
+	copy(hdr[:], id)
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(size))
+	if _, err := a.w.Write(hdr[:]); err != nil {
+		return fmt.Errorf("failed to write AVI chunk: %w", err)
+	}
+	if _, err := a.w.Write(data); err != nil {
+		return fmt.Errorf("failed to write AVI chunk: %w", err)
+	}
+	if size&1 != 0 {
+		if err := a.w.WriteByte(0); err != nil {
+			return fmt.Errorf("failed to write AVI chunk: %w", err)
+		}
+	}
+
+	a.index = append(a.index, aviIndexEntry{id: id, offset: uint32(a.pos - a.moviStart), size: uint32(size)})
+	a.pos += 8 + padded
+	return nil
+}
+
+// WriteFrame 写入一帧 RGB24 像素（每像素 3 字节，自上而下按行排列）
+func (a *AVIWriter) WriteFrame(rgb []byte) error {
+	stride := a.width * 3
+	for y := 0; y < a.height; y++ {
+		src := rgb[y*stride : (y+1)*stride]
+		dst := a.frame[(a.height-1-y)*stride:]
+		for x := 0; x < stride; x += 3 {
+			dst[x], dst[x+1], dst[x+2] = src[x+2], src[x+1], src[x]
+		}
+	}
+
+	if err := a.writeChunk("00db", a.frame); err != nil {
+		return err
+	}
+	a.frames++
+	return nil
+}
+
+// WriteSamples 写入交错的左右声道样本
+func (a *AVIWriter) WriteSamples(samples []int16) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	if err := a.writeChunk("01wb", pcmBytes(samples)); err != nil {
+		return err
+	}
+	a.audioSamples += uint32(len(samples) / 2)
+	return nil
+}
+
+func (a *AVIWriter) Close() error {
+	err := a.finish()
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (a *AVIWriter) finish() error {
+	moviEnd := a.pos
+
+	var idx bytes.Buffer
+	idx.WriteString("idx1")
+	binary.Write(&idx, binary.LittleEndian, uint32(len(a.index)*16))
+	for _, e := range a.index {
+		idx.WriteString(e.id)
+		binary.Write(&idx, binary.LittleEndian, [3]uint32{0x10, e.offset, e.size})
+	}
+	if _, err := a.w.Write(idx.Bytes()); err != nil {
+		return fmt.Errorf("failed to write AVI index: %w", err)
+	}
+	if err := a.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write AVI file: %w", err)
+	}
+
+	end := moviEnd + int64(idx.Len())
+	patches := []struct {
+		at  int64
+		val uint32
+	}{
+		{a.riffSizeAt, uint32(end - 8)},
+		{a.totalFramesAt, a.frames},
+		{a.videoLengthAt, a.frames},
+		{a.audioLengthAt, a.audioSamples},
+		{a.moviSizeAt, uint32(moviEnd - a.moviStart)},
+	}
+	for _, p := range patches {
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], p.val)
+		if _, err := a.f.WriteAt(buf[:], p.at); err != nil {
+			return fmt.Errorf("failed to finalize AVI header: %w", err)
+		}
+	}
+	return nil
+}
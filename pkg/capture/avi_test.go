@@ -0,0 +1,150 @@
+package capture
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type riffChunk struct {
+	id   string
+	data []byte
+	// at 是块头在文件中的偏移
+	at int
+}
+
+// readChunks 解析 data 中依次排列的 RIFF 块，base 为 data 在文件中的偏移
+func readChunks(t *testing.T, data []byte, base int) []riffChunk {
+	t.Helper()
+	var chunks []riffChunk
+	for i := 0; i < len(data); {
+		if i+8 > len(data) {
+			t.Fatalf("truncated chunk header at %d", base+i)
+		}
+		id := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if i+8+size > len(data) {
+			t.Fatalf("chunk %q at %d: size %d overruns its parent", id, base+i, size)
+		}
+		chunks = append(chunks, riffChunk{id: id, data: data[i+8 : i+8+size], at: base + i})
+		i += 8 + size + size&1
+	}
+	return chunks
+}
+
+// list 返回 LIST 块的类型和子块
+func list(t *testing.T, c riffChunk) (string, []riffChunk) {
+	t.Helper()
+	if c.id != "LIST" {
+		t.Fatalf("chunk at %d is %q, want LIST", c.at, c.id)
+	}
+	return string(c.data[:4]), readChunks(t, c.data[4:], c.at+12)
+}
+
+func TestAVIWriter(t *testing.T) {
+	const (
+		width      = 4
+		height     = 2
+		sampleRate = 32768
+	)
+	path := filepath.Join(t.TempDir(), "test.avi")
+
+	a, err := NewAVIWriter(path, width, height, sampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgb := make([]byte, width*height*3)
+	for i := range rgb {
+		rgb[i] = byte(i)
+	}
+	audio := [][]int16{{1, 2, 3, 4}, {}, {5, 6}}
+	for i := 0; i < 3; i++ {
+		if err := a.WriteFrame(rgb); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.WriteSamples(audio[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top := readChunks(t, data, 0)
+	if len(top) != 1 || top[0].id != "RIFF" || string(top[0].data[:4]) != "AVI " {
+		t.Fatalf("file does not start with RIFF/AVI")
+	}
+	if got := len(top[0].data) + 8; got != len(data) {
+		t.Errorf("RIFF size covers %d bytes, file is %d", got, len(data))
+	}
+
+	body := readChunks(t, top[0].data[4:], 12)
+	if len(body) != 3 {
+		t.Fatalf("RIFF body has %d chunks, want hdrl, movi, idx1", len(body))
+	}
+
+	// 头部：avih 的总帧数，两条流的长度
+	kind, hdrl := list(t, body[0])
+	if kind != "hdrl" || hdrl[0].id != "avih" || len(hdrl[0].data) != 56 {
+		t.Fatalf("bad hdrl/avih")
+	}
+	if got := binary.LittleEndian.Uint32(hdrl[0].data[16:]); got != 3 {
+		t.Errorf("avih total frames = %d, want 3", got)
+	}
+	wantLength := map[string]uint32{"vids": 3, "auds": 3}
+	for _, c := range hdrl[1:] {
+		_, strl := list(t, c)
+		strh := strl[0].data
+		kind := string(strh[:4])
+		if got := binary.LittleEndian.Uint32(strh[32:]); got != wantLength[kind] {
+			t.Errorf("%s stream length = %d, want %d", kind, got, wantLength[kind])
+		}
+	}
+
+	// movi：3 个视频块，2 个非空音频块
+	kind, movi := list(t, body[1])
+	if kind != "movi" {
+		t.Fatalf("second list is %q, want movi", kind)
+	}
+	var ids []string
+	for _, c := range movi {
+		ids = append(ids, c.id)
+		if c.id == "00db" && len(c.data) != width*height*3 {
+			t.Errorf("video chunk size %d, want %d", len(c.data), width*height*3)
+		}
+	}
+	if got, want := strings.Join(ids, " "), "00db 01wb 00db 00db 01wb"; got != want {
+		t.Errorf("movi chunks %s, want %s", got, want)
+	}
+	if got := len(movi[1].data); got != 8 {
+		t.Errorf("audio chunk size %d, want 8", got)
+	}
+
+	// 视频自下而上、BGR 顺序存储
+	frame := movi[0].data
+	if frame[0] != rgb[width*3+2] || frame[2] != rgb[width*3] {
+		t.Errorf("first stored pixel % X, want bottom-left BGR", frame[:3])
+	}
+
+	// idx1 的偏移相对 movi 类型字段，指向块头
+	if body[2].id != "idx1" || len(body[2].data) != len(movi)*16 {
+		t.Fatalf("idx1 has %d bytes, want %d", len(body[2].data), len(movi)*16)
+	}
+	moviStart := body[1].at + 8
+	for i, c := range movi {
+		e := body[2].data[i*16:]
+		offset := int(binary.LittleEndian.Uint32(e[8:]))
+		size := int(binary.LittleEndian.Uint32(e[12:]))
+		if string(e[:4]) != c.id || moviStart+offset != c.at || size != len(c.data) {
+			t.Errorf("index entry %d = %s +%d %d, want %s at %d size %d",
+				i, e[:4], offset, size, c.id, c.at-moviStart, len(c.data))
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"math"
+	"os"
+	"sort"
+
+	"gba/pkg/ppu"
+)
+
+// WriteGIF 把按时间顺序排列的帧编码为循环播放的 GIF。
+// step 是相邻两帧之间的模拟帧数，用来按 59.7275 Hz 计算每帧的延时（GIF 以 1/100 秒计）
+func WriteGIF(path string, frames [][]uint16, step, scale int, profile ppu.ColorProfile) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to write")
+	}
+	if scale < 1 {
+		scale = 1
+	}
+
+	table := ppu.ColorTable(profile)
+	anim := &gif.GIF{LoopCount: 0}
+
+	for i, frame := range frames {
+		pal, lookup := quantize(frame, 256)
+		palette := make(color.Palette, len(pal))
+		for j, c := range pal {
+			palette[j] = table[c]
+		}
+
+		img := image.NewPaletted(image.Rect(0, 0, ppu.ScreenWidth*scale, ppu.ScreenHeight*scale), palette)
+		for y := 0; y < ppu.ScreenHeight*scale; y++ {
+			row := img.Pix[y*img.Stride:]
+			src := frame[(y/scale)*ppu.ScreenWidth:]
+			for x := 0; x < ppu.ScreenWidth*scale; x++ {
+				row[x] = lookup[src[x/scale]&0x7FFF]
+			}
+		}
+
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, gifDelay(i, step))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	// 编码失败时删除写了一半的文件
+	if err := gif.EncodeAll(f, anim); err != nil {
+		f.Close()
+		os.Remove(path)
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// gifDelay 返回第 i 帧的延时，取相邻两帧时间戳取整后的差，累计误差不会漂移
+func gifDelay(i, step int) int {
+	at := func(n int) int {
+		return int(math.Round(float64(n*step) * 100 * FrameRateDen / FrameRateNum))
+	}
+	return at(i+1) - at(i)
+}
+
+// quantize 为一帧 15 位颜色生成至多 max 色的调色板，返回调色板和 15 位颜色到索引的映射。
+// 颜色数不超过 max 时完全无损；否则用中位切分按像素数划分颜色空间
+func quantize(frame []uint16, max int) ([]uint16, *[0x8000]uint8) {
+	var hist [0x8000]int
+	var used []uint16
+	for _, c := range frame {
+		c &= 0x7FFF
+		if hist[c] == 0 {
+			used = append(used, c)
+		}
+		hist[c]++
+	}
+
+	lookup := new([0x8000]uint8)
+	if len(used) <= max {
+		for i, c := range used {
+			lookup[c] = uint8(i)
+		}
+		return used, lookup
+	}
+
+	boxes := [][]uint16{used}
+	for len(boxes) < max {
+		// 切分像素数最多且还能再分的盒子
+		best := -1
+		bestCount := 0
+		for i, b := range boxes {
+			if len(b) < 2 {
+				continue
+			}
+			n := 0
+			for _, c := range b {
+				n += hist[c]
+			}
+			if n > bestCount {
+				best, bestCount = i, n
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		a, b := splitBox(boxes[best], hist[:])
+		boxes[best] = a
+		boxes = append(boxes, b)
+	}
+
+	pal := make([]uint16, len(boxes))
+	for i, b := range boxes {
+		pal[i] = boxAverage(b, hist[:])
+		for _, c := range b {
+			lookup[c] = uint8(i)
+		}
+	}
+	return pal, lookup
+}
+
+// splitBox 沿跨度最大的通道在像素数的中位处把盒子分为两半
+func splitBox(box []uint16, hist []int) ([]uint16, []uint16) {
+	shift := uint(0)
+	widest := -1
+	for s := uint(0); s < 15; s += 5 {
+		lo, hi := 31, 0
+		for _, c := range box {
+			v := int(c>>s) & 0x1F
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		if hi-lo > widest {
+			shift, widest = s, hi-lo
+		}
+	}
+
+	sort.Slice(box, func(i, j int) bool {
+		return (box[i]>>shift)&0x1F < (box[j]>>shift)&0x1F
+	})
+
+	total := 0
+	for _, c := range box {
+		total += hist[c]
+	}
+	half, mid := 0, 1
+	for i, c := range box[:len(box)-1] {
+		half += hist[c]
+		mid = i + 1
+		if half*2 >= total {
+			break
+		}
+	}
+	return box[:mid], box[mid:]
+}
+
+func boxAverage(box []uint16, hist []int) uint16 {
+	var sum [3]int
+	n := 0
+	for _, c := range box {
+		w := hist[c]
+		for i := range sum {
+			sum[i] += (int(c>>(uint(i)*5)) & 0x1F) * w
+		}
+		n += w
+	}
+
+	var out uint16
+	for i := range sum {
+		out |= uint16((sum[i]+n/2)/n) << (uint(i) * 5)
+	}
+	return out
+}
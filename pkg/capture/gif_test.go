@@ -0,0 +1,113 @@
+package capture
+
+import (
+	"math"
+	"testing"
+)
+
+func channelError(a, b uint16) int {
+	worst := 0
+	for s := uint(0); s < 15; s += 5 {
+		d := int(a>>s&0x1F) - int(b>>s&0x1F)
+		if d < 0 {
+			d = -d
+		}
+		if d > worst {
+			worst = d
+		}
+	}
+	return worst
+}
+
+func TestQuantizeLossless(t *testing.T) {
+	frame := []uint16{0x7FFF, 0x001F, 0x801F, 0x03E0, 0x7FFF, 0}
+	pal, lookup := quantize(frame, 4)
+
+	// 第 15 位被忽略，颜色按首次出现的顺序排列
+	want := []uint16{0x7FFF, 0x001F, 0x03E0, 0}
+	if len(pal) != len(want) {
+		t.Fatalf("palette %04X, want %04X", pal, want)
+	}
+	for i := range want {
+		if pal[i] != want[i] {
+			t.Fatalf("palette %04X, want %04X", pal, want)
+		}
+	}
+	for _, c := range frame {
+		if got := pal[lookup[c&0x7FFF]]; got != c&0x7FFF {
+			t.Errorf("color %04X maps to %04X", c, got)
+		}
+	}
+}
+
+func TestQuantizeMedianCut(t *testing.T) {
+	// 测试帧有 1024 种颜色
+	frame := testFrame()
+	pal, lookup := quantize(frame, 256)
+	if len(pal) != 256 {
+		t.Fatalf("palette has %d colors, want 256", len(pal))
+	}
+
+	// 每个调色板颜色都落在映射到它的颜色的包围盒内
+	var lo, hi [256][3]int
+	for i := range lo {
+		lo[i] = [3]int{31, 31, 31}
+	}
+	total := 0
+	for _, c := range frame {
+		i := lookup[c]
+		for ch := 0; ch < 3; ch++ {
+			v := int(c>>(uint(ch)*5)) & 0x1F
+			lo[i][ch] = min(lo[i][ch], v)
+			hi[i][ch] = max(hi[i][ch], v)
+		}
+		total += channelError(c, pal[i])
+	}
+	for i, c := range pal {
+		for ch := 0; ch < 3; ch++ {
+			v := int(c>>(uint(ch)*5)) & 0x1F
+			if v < lo[i][ch] || v > hi[i][ch] {
+				t.Errorf("palette %d = %04X outside its box %v-%v", i, c, lo[i], hi[i])
+			}
+		}
+	}
+
+	// 平均误差不到一级
+	if mean := float64(total) / float64(len(frame)); mean > 1 {
+		t.Errorf("mean channel error %.2f, want at most 1", mean)
+	}
+}
+
+func TestQuantizeKeepsDominantColor(t *testing.T) {
+	// 占一半像素的颜色会被单独切出来，平均值就是它本身
+	const dominant = 13 | 7<<5 | 21<<10
+	frame := testFrame()
+	for i := 0; i < len(frame); i += 2 {
+		frame[i] = dominant
+	}
+	pal, lookup := quantize(frame, 16)
+	if len(pal) != 16 {
+		t.Fatalf("palette has %d colors, want 16", len(pal))
+	}
+	if got := pal[lookup[dominant]]; got != dominant {
+		t.Errorf("dominant color %04X maps to %04X", dominant, got)
+	}
+}
+
+func TestGIFDelay(t *testing.T) {
+	for _, step := range []int{1, 2, 3} {
+		sum := 0
+		for i := 0; i < 600; i++ {
+			d := gifDelay(i, step)
+			if d < 1 || d > 2*step {
+				t.Fatalf("step %d: frame %d delay %d", step, i, d)
+			}
+			sum += d
+		}
+		// 延时之和等于总时长取整，不随帧数累计误差
+		want := int(math.Round(float64(600*step) * 100 * FrameRateDen / FrameRateNum))
+		if sum != want {
+			t.Errorf("step %d: total delay %d, want %d", step, sum, want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package capture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// timestampPath 返回 dir 下形如 <前缀>-20060102-150405.000<后缀>.<ext> 的路径，
+// 同一毫秒内重名时追加序号
+func timestampPath(dir, prefix, suffix, ext string, now time.Time) string {
+	if dir == "" {
+		dir = "."
+	}
+
+	name := fmt.Sprintf("%s-%s%s", prefix, now.Format("20060102-150405.000"), suffix)
+	path := filepath.Join(dir, name+ext)
+	for i := 1; fileExists(path); i++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, i, ext))
+	}
+	return path
+}
+
+// OutputPath 返回录像等输出文件的时间戳路径，并确保目录存在
+func OutputPath(dir, prefix, ext string) (string, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return "", fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	return timestampPath(dir, prefix, "", ext, time.Now()), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,112 @@
+package capture
+
+import (
+	"fmt"
+	"gba/pkg/apu"
+	"gba/pkg/ppu"
+	"path/filepath"
+	"strings"
+)
+
+// GBA 帧率 16777216 / 280896 = 59.7275 Hz，约分后的分子和分母
+const (
+	FrameRateNum = 262144
+	FrameRateDen = 4389
+)
+
+// Recorder 逐帧录制画面和声音。每个模拟帧写一帧视频，
+// 声音按 APU 实际产生的样本写入，两者都跟随模拟时间而不是墙钟时间
+type Recorder struct {
+	Path string
+
+	profile ppu.ColorProfile
+	rgb     []byte
+	frames  int
+
+	avi   *AVIWriter
+	y4m   *Y4MWriter
+	wav   *WAVWriter
+	audio string
+}
+
+// NewRecorder 按扩展名选择格式：.avi 为未压缩 AVI，.y4m 为 Y4M 视频加同名 .wav 音轨
+func NewRecorder(path string, profile ppu.ColorProfile) (*Recorder, error) {
+	r := &Recorder{
+		Path:    path,
+		profile: profile,
+		rgb:     make([]byte, ppu.ScreenWidth*ppu.ScreenHeight*3),
+	}
+
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".avi":
+		r.avi, err = NewAVIWriter(path, ppu.ScreenWidth, ppu.ScreenHeight, apu.SampleRate)
+	case ".y4m":
+		r.y4m, err = NewY4MWriter(path, ppu.ScreenWidth, ppu.ScreenHeight)
+		if err != nil {
+			return nil, err
+		}
+		r.audio = strings.TrimSuffix(path, filepath.Ext(path)) + ".wav"
+		r.wav, err = NewWAVWriter(r.audio, apu.SampleRate)
+		if err != nil {
+			r.y4m.Close()
+		}
+	default:
+		return nil, fmt.Errorf("unsupported recording format %q (use .avi or .y4m)", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// WriteFrame 写入一帧 240x160 的 BGR555 画面
+func (r *Recorder) WriteFrame(frame []uint16) error {
+	table := ppu.ColorTable(r.profile)
+	for i, c := range frame {
+		rgba := table[c&0x7FFF]
+		r.rgb[i*3], r.rgb[i*3+1], r.rgb[i*3+2] = rgba.R, rgba.G, rgba.B
+	}
+
+	var err error
+	if r.avi != nil {
+		err = r.avi.WriteFrame(r.rgb)
+	} else {
+		err = r.y4m.WriteFrame(r.rgb)
+	}
+	if err == nil {
+		r.frames++
+	}
+	return err
+}
+
+// WriteAudio 写入交错的左右声道样本
+func (r *Recorder) WriteAudio(samples []int16) error {
+	if r.avi != nil {
+		return r.avi.WriteSamples(samples)
+	}
+	return r.wav.WriteSamples(samples)
+}
+
+func (r *Recorder) Frames() int {
+	return r.frames
+}
+
+// Files 返回录像产生的文件
+func (r *Recorder) Files() []string {
+	if r.audio != "" {
+		return []string{r.Path, r.audio}
+	}
+	return []string{r.Path}
+}
+
+func (r *Recorder) Close() error {
+	if r.avi != nil {
+		return r.avi.Close()
+	}
+	err := r.y4m.Close()
+	if werr := r.wav.Close(); err == nil {
+		err = werr
+	}
+	return err
+}
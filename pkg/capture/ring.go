@@ -0,0 +1,72 @@
+package capture
+
+import (
+	"gba/pkg/ppu"
+	"sync"
+)
+
+// FrameRing 保存最近若干帧画面，模拟线程写入，其他线程可随时取出快照
+type FrameRing struct {
+	mu     sync.Mutex
+	frames [][]uint16
+	next   int
+	count  int
+
+	// Step 为抽帧间隔，每 Step 个模拟帧保存一帧
+	Step    int
+	pending int
+}
+
+// NewFrameRing 创建能保存最近 seconds 秒画面的环形缓冲，每 step 帧保存一帧
+func NewFrameRing(seconds float64, step int) *FrameRing {
+	if step < 1 {
+		step = 1
+	}
+	n := int(seconds * FrameRateNum / FrameRateDen / float64(step))
+	if n < 1 {
+		n = 1
+	}
+
+	frames := make([][]uint16, n)
+	for i := range frames {
+		frames[i] = make([]uint16, ppu.ScreenWidth*ppu.ScreenHeight)
+	}
+	return &FrameRing{frames: frames, Step: step}
+}
+
+// Push 记录一个模拟帧，按抽帧间隔决定是否保存
+func (r *FrameRing) Push(frame []uint16) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending++
+	if r.pending < r.Step {
+		return
+	}
+	r.pending = 0
+
+	copy(r.frames[r.next], frame)
+	r.next = (r.next + 1) % len(r.frames)
+	if r.count < len(r.frames) {
+		r.count++
+	}
+}
+
+// Snapshot 按时间顺序返回已保存帧的副本
+func (r *FrameRing) Snapshot() [][]uint16 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([][]uint16, r.count)
+	start := (r.next - r.count + len(r.frames)) % len(r.frames)
+	for i := range out {
+		out[i] = append([]uint16(nil), r.frames[(start+i)%len(r.frames)]...)
+	}
+	return out
+}
+
+func (r *FrameRing) Clear() {
+	r.mu.Lock()
+	r.next, r.count, r.pending = 0, 0, 0
+	r.mu.Unlock()
+}
@@ -0,0 +1,86 @@
+package capture
+
+import (
+	"testing"
+
+	"gba/pkg/ppu"
+)
+
+func ringFrame(n uint16) []uint16 {
+	frame := make([]uint16, ppu.ScreenWidth*ppu.ScreenHeight)
+	frame[0] = n
+	return frame
+}
+
+func ringContents(r *FrameRing) []uint16 {
+	var out []uint16
+	for _, f := range r.Snapshot() {
+		out = append(out, f[0])
+	}
+	return out
+}
+
+func expectRing(t *testing.T, name string, got, want []uint16) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: ring holds %v, want %v", name, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: ring holds %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestFrameRingCapacity(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		step    int
+		want    int
+	}{
+		{1, 1, 59},
+		{1, 2, 29},
+		{10, 3, 199},
+		{0, 1, 1},
+		// 非法的间隔按 1 处理
+		{1, 0, 59},
+	}
+	for _, tt := range tests {
+		r := NewFrameRing(tt.seconds, tt.step)
+		if len(r.frames) != tt.want || r.Step < 1 {
+			t.Errorf("NewFrameRing(%v, %d): %d frames step %d, want %d",
+				tt.seconds, tt.step, len(r.frames), r.Step, tt.want)
+		}
+	}
+}
+
+func TestFrameRingOrder(t *testing.T) {
+	// 4 帧容量，每 2 帧保存一帧
+	r := NewFrameRing(8.5*FrameRateDen/FrameRateNum, 2)
+	if len(r.frames) != 4 {
+		t.Fatalf("ring has %d frames, want 4", len(r.frames))
+	}
+	expectRing(t, "empty", ringContents(r), nil)
+
+	for i := uint16(1); i <= 5; i++ {
+		r.Push(ringFrame(i))
+	}
+	expectRing(t, "partial", ringContents(r), []uint16{2, 4})
+
+	for i := uint16(6); i <= 13; i++ {
+		r.Push(ringFrame(i))
+	}
+	expectRing(t, "wrapped", ringContents(r), []uint16{6, 8, 10, 12})
+
+	// 快照是副本，修改不影响缓冲
+	snap := r.Snapshot()
+	snap[0][0] = 99
+	expectRing(t, "after modifying snapshot", ringContents(r), []uint16{6, 8, 10, 12})
+
+	// 清空后抽帧计数也从头开始
+	r.Clear()
+	expectRing(t, "cleared", ringContents(r), nil)
+	r.Push(ringFrame(20))
+	r.Push(ringFrame(21))
+	expectRing(t, "after clear", ringContents(r), []uint16{21})
+}
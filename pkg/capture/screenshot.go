@@ -0,0 +1,81 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"time"
+)
+
+// Screenshotter 把画面保存为带时间戳和帧号的 PNG
+type Screenshotter struct {
+	// Dir 为空时保存到当前目录
+	Dir string
+	// Scale 为整数放大倍数，小于 1 时按原始分辨率保存
+	Scale int
+	// Prefix 是文件名前缀，通常为 ROM 文件名
+	Prefix string
+
+	Now func() time.Time
+}
+
+func NewScreenshotter(dir string) *Screenshotter {
+	return &Screenshotter{
+		Dir:    dir,
+		Scale:  1,
+		Prefix: "gba",
+		Now:    time.Now,
+	}
+}
+
+// Save 写出一张截图并返回文件路径，文件名形如 <前缀>-20060102-150405.000-f<帧号>.png
+func (s *Screenshotter) Save(img *image.RGBA, frame uint64) (string, error) {
+	if s.Dir != "" {
+		if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+			return "", fmt.Errorf("failed to create screenshot directory: %w", err)
+		}
+	}
+
+	path := timestampPath(s.Dir, s.Prefix, fmt.Sprintf("-f%d", frame), ".png", s.Now())
+	if err := WritePNG(path, ScaleImage(img, s.Scale)); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func WritePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// ScaleImage 按整数倍做最近邻放大，scale <= 1 时原样返回
+func ScaleImage(src *image.RGBA, scale int) *image.RGBA {
+	if scale <= 1 {
+		return src
+	}
+
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()*scale, b.Dy()*scale))
+	for y := 0; y < b.Dy(); y++ {
+		srcRow := src.Pix[y*src.Stride : y*src.Stride+b.Dx()*4]
+		dstRow := dst.Pix[y*scale*dst.Stride : y*scale*dst.Stride+dst.Stride]
+		for x := 0; x < b.Dx(); x++ {
+			for i := 0; i < scale; i++ {
+				copy(dstRow[(x*scale+i)*4:], srcRow[x*4:x*4+4])
+			}
+		}
+		for i := 1; i < scale; i++ {
+			copy(dst.Pix[(y*scale+i)*dst.Stride:], dstRow)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,83 @@
+package capture
+
+import (
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gba/pkg/ppu"
+)
+
+func testFrame() []uint16 {
+	frame := make([]uint16, ppu.ScreenWidth*ppu.ScreenHeight)
+	for i := range frame {
+		x, y := i%ppu.ScreenWidth, i/ppu.ScreenWidth
+		frame[i] = uint16(x&0x1F) | uint16(y&0x1F)<<5 | uint16((x+y)&0x1F)<<10
+	}
+	return frame
+}
+
+func TestScreenshotPNGRoundTrip(t *testing.T) {
+	for _, scale := range []int{1, 3} {
+		s := NewScreenshotter(filepath.Join(t.TempDir(), "shots"))
+		s.Scale = scale
+		s.Prefix = "test"
+		s.Now = func() time.Time { return time.Date(2026, time.October, 18, 15, 4, 5, 123e6, time.UTC) }
+
+		frame := testFrame()
+		path, err := s.Save(ppu.FrameToImage(frame, ppu.ColorNone), 42)
+		if err != nil {
+			t.Fatalf("scale %d: Save: %v", scale, err)
+		}
+		if got, want := filepath.Base(path), "test-20261018-150405.123-f42.png"; got != want {
+			t.Errorf("scale %d: file name %s, want %s", scale, got, want)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("scale %d: decode: %v", scale, err)
+		}
+
+		b := img.Bounds()
+		if b.Dx() != ppu.ScreenWidth*scale || b.Dy() != ppu.ScreenHeight*scale {
+			t.Fatalf("scale %d: size %dx%d", scale, b.Dx(), b.Dy())
+		}
+
+		table := ppu.ColorTable(ppu.ColorNone)
+		for y := 0; y < b.Dy(); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				want := table[frame[(y/scale)*ppu.ScreenWidth+x/scale]]
+				got := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+				if got != want {
+					t.Fatalf("scale %d: pixel (%d,%d) = %v, want %v", scale, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestScreenshotNameCollision(t *testing.T) {
+	s := NewScreenshotter(t.TempDir())
+	s.Now = func() time.Time { return time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC) }
+	img := ppu.FrameToImage(testFrame(), ppu.ColorNone)
+
+	first, err := s.Save(img, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Save(img, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second || filepath.Base(second) != "gba-20261018-000000.000-f1-1.png" {
+		t.Errorf("second screenshot %s overwrote or misnamed %s", second, first)
+	}
+}
@@ -0,0 +1,84 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// WAVWriter 写出 16 位立体声 PCM，文件长度在 Close 时回填
+type WAVWriter struct {
+	f          *os.File
+	sampleRate int
+	dataBytes  uint32
+}
+
+const wavHeaderSize = 44
+
+func NewWAVWriter(path string, sampleRate int) (*WAVWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	w := &WAVWriter{f: f, sampleRate: sampleRate}
+	if err := w.writeHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *WAVWriter) writeHeader() error {
+	var h [wavHeaderSize]byte
+	le := binary.LittleEndian
+
+	copy(h[0:], "RIFF")
+	le.PutUint32(h[4:], 36+w.dataBytes)
+	copy(h[8:], "WAVE")
+	copy(h[12:], "fmt ")
+	le.PutUint32(h[16:], 16)
+	le.PutUint16(h[20:], 1) // PCM
+	le.PutUint16(h[22:], 2)
+	le.PutUint32(h[24:], uint32(w.sampleRate))
+	le.PutUint32(h[28:], uint32(w.sampleRate*4))
+	le.PutUint16(h[32:], 4)
+	le.PutUint16(h[34:], 16)
+	copy(h[36:], "data")
+	le.PutUint32(h[40:], w.dataBytes)
+
+	if _, err := w.f.WriteAt(h[:], 0); err != nil {
+		return fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	return nil
+}
+
+// WriteSamples 写入交错的左右声道样本
+func (w *WAVWriter) WriteSamples(samples []int16) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	buf := pcmBytes(samples)
+	if _, err := w.f.WriteAt(buf, int64(wavHeaderSize+w.dataBytes)); err != nil {
+		return fmt.Errorf("failed to write WAV data: %w", err)
+	}
+	w.dataBytes += uint32(len(buf))
+	return nil
+}
+
+func (w *WAVWriter) Close() error {
+	err := w.writeHeader()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func pcmBytes(samples []int16) []byte {
+	buf := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(buf[i*2:], uint16(s))
+	}
+	return buf
+}
@@ -0,0 +1,68 @@
+package capture
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.wav")
+	w, err := NewWAVWriter(path, 32768)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, samples := range [][]int16{{1, -1, 2, -2}, nil, {0x7FFF, -0x8000}} {
+		if err := w.WriteSamples(samples); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != wavHeaderSize+12 {
+		t.Fatalf("file is %d bytes, want %d", len(data), wavHeaderSize+12)
+	}
+
+	le := binary.LittleEndian
+	fields := []struct {
+		name string
+		got  uint32
+		want uint32
+	}{
+		{"RIFF size", le.Uint32(data[4:]), uint32(len(data) - 8)},
+		{"fmt size", le.Uint32(data[16:]), 16},
+		{"format", uint32(le.Uint16(data[20:])), 1},
+		{"channels", uint32(le.Uint16(data[22:])), 2},
+		{"sample rate", le.Uint32(data[24:]), 32768},
+		{"byte rate", le.Uint32(data[28:]), 32768 * 4},
+		{"block align", uint32(le.Uint16(data[32:])), 4},
+		{"bits", uint32(le.Uint16(data[34:])), 16},
+		{"data size", le.Uint32(data[40:]), 12},
+	}
+	for _, f := range fields {
+		if f.got != f.want {
+			t.Errorf("%s = %d, want %d", f.name, f.got, f.want)
+		}
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:16]) != "WAVEfmt " || string(data[36:40]) != "data" {
+		t.Errorf("bad chunk ids in header % X", data[:wavHeaderSize])
+	}
+
+	var samples []int16
+	for i := wavHeaderSize; i < len(data); i += 2 {
+		samples = append(samples, int16(le.Uint16(data[i:])))
+	}
+	want := []int16{1, -1, 2, -2, 0x7FFF, -0x8000}
+	for i := range want {
+		if samples[i] != want[i] {
+			t.Fatalf("samples = %v, want %v", samples, want)
+		}
+	}
+}
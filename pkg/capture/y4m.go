@@ -0,0 +1,72 @@
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+// Y4MWriter 写出 4:4:4 采样的 YUV4MPEG2 视频，帧率固定为 GBA 的 59.7275 Hz
+type Y4MWriter struct {
+	f      *os.File
+	w      *bufio.Writer
+	width  int
+	height int
+	plane  []byte
+}
+
+func NewY4MWriter(path string, width, height int) (*Y4MWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	y := &Y4MWriter{
+		f:      f,
+		w:      bufio.NewWriter(f),
+		width:  width,
+		height: height,
+		plane:  make([]byte, width*height*3),
+	}
+	_, err = fmt.Fprintf(y.w, "YUV4MPEG2 W%d H%d F%d:%d Ip A1:1 C444\n",
+		width, height, FrameRateNum, FrameRateDen)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write Y4M header: %w", err)
+	}
+	return y, nil
+}
+
+// WriteFrame 写入一帧 RGB24 像素（每像素 3 字节，按行排列）
+func (y *Y4MWriter) WriteFrame(rgb []byte) error {
+	n := y.width * y.height
+	yp, up, vp := y.plane[:n], y.plane[n:2*n], y.plane[2*n:]
+	for i := 0; i < n; i++ {
+		yp[i], up[i], vp[i] = rgbToYUV(rgb[i*3], rgb[i*3+1], rgb[i*3+2])
+	}
+
+	if _, err := y.w.WriteString("FRAME\n"); err != nil {
+		return fmt.Errorf("failed to write Y4M frame: %w", err)
+	}
+	if _, err := y.w.Write(y.plane); err != nil {
+		return fmt.Errorf("failed to write Y4M frame: %w", err)
+	}
+	return nil
+}
+
+func (y *Y4MWriter) Close() error {
+	err := y.w.Flush()
+	if cerr := y.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// rgbToYUV 按 BT.601 有限范围转换
+func rgbToYUV(r, g, b byte) (byte, byte, byte) {
+	ri, gi, bi := int(r), int(g), int(b)
+	yy := (66*ri+129*gi+25*bi+128)>>8 + 16
+	u := (-38*ri-74*gi+112*bi+128)>>8 + 128
+	v := (112*ri-94*gi-18*bi+128)>>8 + 128
+	return byte(yy), byte(u), byte(v)
+}
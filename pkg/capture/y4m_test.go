@@ -0,0 +1,60 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestY4MWriter(t *testing.T) {
+	const width, height = 4, 2
+	path := filepath.Join(t.TempDir(), "test.y4m")
+	y, err := NewY4MWriter(path, width, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一个像素白色，其余黑色
+	rgb := make([]byte, width*height*3)
+	rgb[0], rgb[1], rgb[2] = 255, 255, 255
+	for i := 0; i < 3; i++ {
+		if err := y.WriteFrame(rgb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := y.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := fmt.Sprintf("YUV4MPEG2 W4 H2 F%d:%d Ip A1:1 C444\n", FrameRateNum, FrameRateDen)
+	if !bytes.HasPrefix(data, []byte(header)) {
+		t.Fatalf("header %q, want %q", data[:bytes.IndexByte(data, '\n')+1], header)
+	}
+
+	frameSize := len("FRAME\n") + width*height*3
+	body := data[len(header):]
+	if len(body) != 3*frameSize {
+		t.Fatalf("body is %d bytes, want 3 frames of %d", len(body), frameSize)
+	}
+	for i := 0; i < 3; i++ {
+		frame := body[i*frameSize : (i+1)*frameSize]
+		if !bytes.HasPrefix(frame, []byte("FRAME\n")) {
+			t.Fatalf("frame %d has no FRAME marker", i)
+		}
+		planes := frame[len("FRAME\n"):]
+		// BT.601 有限范围：白色 Y=235，黑色 Y=16，灰色的 U/V 都是 128
+		if planes[0] != 235 || planes[1] != 16 {
+			t.Errorf("frame %d Y plane starts % d, want 235 16", i, planes[:2])
+		}
+		if planes[width*height] != 128 || planes[2*width*height] != 128 {
+			t.Errorf("frame %d U/V = %d/%d, want 128/128", i, planes[width*height], planes[2*width*height])
+		}
+	}
+}
@@ -14,6 +14,16 @@ type Cartridge struct {
 	UnitCode  byte
 	Version   byte
 	Checksum  byte
+
+	// 带对应外设的卡带才会创建，否则为 nil
+	GPIO   *GPIO
+	RTC    *RTC
+	Solar  *SolarSensor
+	Gyro   *Gyro
+	Rumble *RumbleMotor
+	Tilt   *Tilt
+
+	saveType SaveType
 }
 
 func Load(filename string) (*Cartridge, error) {
@@ -37,6 +47,8 @@ func Load(filename string) (*Cartridge, error) {
 	}
 
 	cart.parseHeader()
+	cart.saveType = cart.detectSaveType()
+	cart.setupHardware()
 	fmt.Printf("[Cartridge] ROM Info: %s\n", cart.String())
 
 	return cart, nil
@@ -63,6 +75,46 @@ func (c *Cartridge) parseHeader() {
 	}
 }
 
+func (c *Cartridge) setupHardware() {
+	override, ok := overrides[c.GameCode]
+	if !ok || override.Hardware == 0 {
+		return
+	}
+
+	// 倾斜传感器挂在 SRAM 区域，不占用 GPIO
+	if override.Hardware&HardwareTilt != 0 {
+		c.Tilt = NewTilt()
+		fmt.Printf("[Cartridge] Tilt sensor enabled\n")
+	}
+
+	if override.Hardware&^HardwareTilt == 0 {
+		return
+	}
+
+	c.GPIO = NewGPIO()
+
+	if override.Hardware&HardwareRTC != 0 {
+		c.RTC = NewRTC()
+		c.GPIO.Attach(c.RTC)
+		fmt.Printf("[Cartridge] RTC enabled\n")
+	}
+	if override.Hardware&HardwareLight != 0 {
+		c.Solar = NewSolarSensor()
+		c.GPIO.Attach(c.Solar)
+		fmt.Printf("[Cartridge] Solar sensor enabled\n")
+	}
+	if override.Hardware&HardwareGyro != 0 {
+		c.Gyro = NewGyro()
+		c.GPIO.Attach(c.Gyro)
+		fmt.Printf("[Cartridge] Gyro sensor enabled\n")
+	}
+	if override.Hardware&HardwareRumble != 0 {
+		c.Rumble = NewRumbleMotor()
+		c.GPIO.Attach(c.Rumble)
+		fmt.Printf("[Cartridge] Rumble enabled\n")
+	}
+}
+
 func (c *Cartridge) GetROM() []byte {
 	return c.ROM
 }
@@ -92,28 +144,7 @@ func (c *Cartridge) Read32(addr uint32) uint32 {
 	return 0
 }
 
-func (c *Cartridge) GetSaveType() string {
-	if len(c.ROM) < 0xE4 {
-		return "SRAM"
-	}
-
-	id := binary.LittleEndian.Uint32(c.ROM[0xAC:])
-
-	switch id {
-	case 0x45564153:
-		return "SRAM"
-	case 0x52414D5F:
-		return "EEPROM"
-	case 0x53414D5F:
-		return "SRAM"
-	case 0x53414D46:
-		return "FLASH"
-	default:
-		return "SRAM"
-	}
-}
-
 func (c *Cartridge) String() string {
-	return fmt.Sprintf("Title: %s, GameCode: %s, Maker: %s, Size: %d bytes",
-		c.Title, c.GameCode, c.MakerCode, len(c.ROM))
+	return fmt.Sprintf("Title: %s, GameCode: %s, Maker: %s, Size: %d bytes, Save: %s",
+		c.Title, c.GameCode, c.MakerCode, len(c.ROM), c.saveType)
 }
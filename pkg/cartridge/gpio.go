@@ -0,0 +1,80 @@
+package cartridge
+
+// GPIO 寄存器覆盖在 ROM 的 0xC4-0xC9 上
+const (
+	GPIOData      = 0xC4
+	GPIODirection = 0xC6
+	GPIOControl   = 0xC8
+)
+
+// GPIODevice 是接在卡带 4 位 GPIO 端口上的外设
+type GPIODevice interface {
+	// WritePins 在 CPU 写数据寄存器后调用，pins 为 CPU 输出的电平
+	WritePins(pins, direction uint8)
+	// ReadPins 返回设备驱动的电平，只有方向为输入的位会被采用
+	ReadPins() uint8
+}
+
+type GPIO struct {
+	data      uint8
+	direction uint8
+	readable  bool
+
+	devices []GPIODevice
+}
+
+func NewGPIO() *GPIO {
+	return &GPIO{}
+}
+
+func (g *GPIO) Attach(device GPIODevice) {
+	g.devices = append(g.devices, device)
+}
+
+// Readable 对应控制寄存器 bit 0，为 0 时这几个地址读出的是 ROM 原始内容
+func (g *GPIO) Readable() bool {
+	return g.readable
+}
+
+func (g *GPIO) pins() uint8 {
+	var input uint8
+	for _, d := range g.devices {
+		input |= d.ReadPins()
+	}
+	return (g.data & g.direction) | (input &^ g.direction & 0xF)
+}
+
+func (g *GPIO) Read8(offset uint32) uint8 {
+	switch offset {
+	case GPIOData:
+		return g.pins()
+	case GPIODirection:
+		return g.direction
+	case GPIOControl:
+		if g.readable {
+			return 1
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+func (g *GPIO) Write8(offset uint32, val uint8) {
+	switch offset {
+	case GPIOData:
+		g.data = val & 0xF
+		out := g.data & g.direction
+		for _, d := range g.devices {
+			d.WritePins(out, g.direction)
+		}
+	case GPIODirection:
+		g.direction = val & 0xF
+	case GPIOControl:
+		g.readable = val&1 != 0
+	}
+}
+
+func IsGPIOAddress(offset uint32) bool {
+	return offset >= GPIOData && offset < GPIOControl+2
+}
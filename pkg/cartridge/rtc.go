@@ -0,0 +1,187 @@
+package cartridge
+
+import (
+	"fmt"
+	"time"
+)
+
+// S-3511 引脚: bit0 = SCK, bit1 = SIO, bit2 = CS
+const (
+	rtcSCK = 0x1
+	rtcSIO = 0x2
+	rtcCS  = 0x4
+)
+
+// 命令字节按串行顺序（低位先到）组装后: 低 4 位为 0110，bit4-6 为命令，bit7 为读标志
+const (
+	rtcCmdReset    = 0
+	rtcCmdDateTime = 2
+	rtcCmdForceIRQ = 3
+	rtcCmdControl  = 4
+	rtcCmdTime     = 6
+)
+
+var rtcCommandBytes = [8]int{0, 0, 7, 0, 1, 0, 3, 0}
+
+const rtcControl24Hour = 0x40
+
+type RTC struct {
+	// Now 返回当前时间，测试时可以替换成固定时钟
+	Now func() time.Time
+
+	control uint8
+	time    [7]uint8
+
+	transferStep   int
+	bits           uint8
+	bitsRead       int
+	bytesRemaining int
+	command        uint8
+	commandActive  bool
+	pins           uint8
+	output         uint8
+}
+
+func NewRTC() *RTC {
+	rtc := &RTC{
+		Now:     time.Now,
+		control: rtcControl24Hour,
+	}
+	return rtc
+}
+
+func (r *RTC) ReadPins() uint8 {
+	return r.output
+}
+
+func (r *RTC) WritePins(pins, direction uint8) {
+	r.pins = pins
+
+	switch r.transferStep {
+	case 0:
+		// 等待 CS 低、SCK 高的空闲状态
+		if pins&(rtcSCK|rtcCS) == rtcSCK {
+			r.transferStep = 1
+		}
+	case 1:
+		if pins&(rtcSCK|rtcCS) == rtcSCK|rtcCS {
+			r.transferStep = 2
+		} else if pins&(rtcSCK|rtcCS) != rtcSCK {
+			r.transferStep = 0
+		}
+	case 2:
+		if pins&rtcSCK == 0 {
+			// SCK 低电平时采样 SIO
+			r.bits &^= 1 << r.bitsRead
+			r.bits |= ((pins & rtcSIO) >> 1) << r.bitsRead
+			return
+		}
+
+		if pins&rtcCS == 0 {
+			// CS 拉低，传输结束
+			r.bitsRead = 0
+			r.bytesRemaining = 0
+			r.commandActive = false
+			r.command = 0
+			r.transferStep = int(pins & rtcSCK)
+			r.output = rtcSCK
+			return
+		}
+
+		if !r.isReading() {
+			r.bitsRead++
+			if r.bitsRead == 8 {
+				r.processByte()
+			}
+			return
+		}
+
+		r.output = r.outputBit() << 1
+		r.bitsRead++
+		if r.bitsRead == 8 {
+			r.bytesRemaining--
+			if r.bytesRemaining <= 0 {
+				r.commandActive = false
+				r.command = 0
+			}
+			r.bitsRead = 0
+		}
+	}
+}
+
+func (r *RTC) isReading() bool {
+	return r.commandActive && r.command&0x80 != 0
+}
+
+func (r *RTC) processByte() {
+	r.bytesRemaining--
+
+	if !r.commandActive {
+		if r.bits&0xF == 0x6 {
+			r.command = r.bits
+			cmd := (r.command >> 4) & 0x7
+			r.bytesRemaining = rtcCommandBytes[cmd]
+			r.commandActive = r.bytesRemaining > 0
+
+			switch cmd {
+			case rtcCmdReset:
+				r.control = 0
+			case rtcCmdDateTime, rtcCmdTime:
+				r.updateClock()
+			}
+		} else {
+			fmt.Printf("[RTC] Invalid command byte: 0x%02X\n", r.bits)
+		}
+	} else {
+		// 强制 IRQ 命令需要卡带的 IRQ 线，这里没有接，参数字节直接忽略
+		if (r.command>>4)&0x7 == rtcCmdControl {
+			r.control = r.bits
+		}
+	}
+
+	r.bits = 0
+	r.bitsRead = 0
+	if r.bytesRemaining <= 0 {
+		r.commandActive = false
+		r.command = 0
+	}
+}
+
+func (r *RTC) outputBit() uint8 {
+	if !r.commandActive {
+		return 0
+	}
+
+	var out uint8
+	switch (r.command >> 4) & 0x7 {
+	case rtcCmdControl:
+		out = r.control
+	case rtcCmdDateTime, rtcCmdTime:
+		out = r.time[7-r.bytesRemaining]
+	}
+	return (out >> r.bitsRead) & 1
+}
+
+func (r *RTC) updateClock() {
+	now := r.Now()
+
+	r.time[0] = toBCD(now.Year() % 100)
+	r.time[1] = toBCD(int(now.Month()))
+	r.time[2] = toBCD(now.Day())
+	r.time[3] = toBCD(int(now.Weekday()))
+	if r.control&rtcControl24Hour != 0 {
+		r.time[4] = toBCD(now.Hour())
+	} else {
+		r.time[4] = toBCD(now.Hour() % 12)
+		// 12 小时制下 bit 7 为下午标志
+		if now.Hour() >= 12 {
+			r.time[4] |= 0x80
+		}
+	}
+	r.time[5] = toBCD(now.Minute())
+	r.time[6] = toBCD(now.Second())
+}
+
+func toBCD(v int) uint8 {
+	return uint8((v/10)<<4 | v%10)
+}
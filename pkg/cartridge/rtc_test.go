@@ -0,0 +1,136 @@
+package cartridge
+
+import (
+	"testing"
+	"time"
+)
+
+// rtcBus 通过 GPIO 寄存器按游戏的方式驱动 RTC 串行协议
+type rtcBus struct {
+	t    *testing.T
+	gpio *GPIO
+}
+
+func newRTCBus(t *testing.T, now time.Time) *rtcBus {
+	rtc := NewRTC()
+	rtc.Now = func() time.Time { return now }
+
+	gpio := NewGPIO()
+	gpio.Attach(rtc)
+	gpio.Write8(GPIOControl, 1)
+	return &rtcBus{t: t, gpio: gpio}
+}
+
+func (b *rtcBus) pins(val uint8) {
+	b.gpio.Write8(GPIOData, val)
+}
+
+// begin 拉高 CS 开始一次传输，命令字节高位先发
+func (b *rtcBus) begin(cmd uint8) {
+	b.gpio.Write8(GPIODirection, rtcSCK|rtcSIO|rtcCS)
+	b.pins(rtcSCK)
+	b.pins(rtcSCK | rtcCS)
+	for i := 7; i >= 0; i-- {
+		b.writeBit((cmd >> uint(i)) & 1)
+	}
+}
+
+func (b *rtcBus) writeBit(bit uint8) {
+	b.pins(rtcCS | bit<<1)
+	b.pins(rtcCS | rtcSCK | bit<<1)
+}
+
+// writeByte 发送参数字节，低位先发
+func (b *rtcBus) writeByte(val uint8) {
+	for i := 0; i < 8; i++ {
+		b.writeBit((val >> uint(i)) & 1)
+	}
+}
+
+// readBytes 把 SIO 切换为输入，按低位先到读出 n 个字节
+func (b *rtcBus) readBytes(n int) []uint8 {
+	b.gpio.Write8(GPIODirection, rtcSCK|rtcCS)
+
+	out := make([]uint8, n)
+	for i := range out {
+		for bit := 0; bit < 8; bit++ {
+			b.pins(rtcCS)
+			b.pins(rtcCS | rtcSCK)
+			out[i] |= ((b.gpio.Read8(GPIOData) & rtcSIO) >> 1) << uint(bit)
+		}
+	}
+	return out
+}
+
+func (b *rtcBus) end() {
+	b.gpio.Write8(GPIODirection, rtcSCK|rtcSIO|rtcCS)
+	b.pins(rtcSCK)
+}
+
+func (b *rtcBus) expect(name string, got, want []uint8) {
+	b.t.Helper()
+	if string(got) != string(want) {
+		b.t.Errorf("%s = % X, want % X", name, got, want)
+	}
+}
+
+func TestRTCReadDateTime(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 15, 4, 59, 0, time.UTC)
+	bus := newRTCBus(t, now)
+
+	// 0x63 读状态寄存器，上电默认 24 小时制
+	bus.begin(0x63)
+	bus.expect("status", bus.readBytes(1), []uint8{rtcControl24Hour})
+	bus.end()
+
+	// 0x65 读日期时间：年、月、日、星期、时、分、秒，均为 BCD
+	bus.begin(0x65)
+	bus.expect("datetime (24h)", bus.readBytes(7), []uint8{0x26, 0x10, 0x18, 0x00, 0x15, 0x04, 0x59})
+	bus.end()
+
+	// 0x62 写状态寄存器切换到 12 小时制后，小时按 12 取模，bit7 为下午标志
+	bus.begin(0x62)
+	bus.writeByte(0x00)
+	bus.end()
+
+	bus.begin(0x63)
+	bus.expect("status", bus.readBytes(1), []uint8{0x00})
+	bus.end()
+
+	bus.begin(0x65)
+	bus.expect("datetime (12h)", bus.readBytes(7), []uint8{0x26, 0x10, 0x18, 0x00, 0x83, 0x04, 0x59})
+	bus.end()
+}
+
+func TestRTCHourFormat(t *testing.T) {
+	tests := []struct {
+		hour   int
+		want24 uint8
+		want12 uint8
+	}{
+		{0, 0x00, 0x00},
+		{9, 0x09, 0x09},
+		{11, 0x11, 0x11},
+		{12, 0x12, 0x80},
+		{15, 0x15, 0x83},
+		{23, 0x23, 0x91},
+	}
+
+	for _, tt := range tests {
+		now := time.Date(2026, time.October, 18, tt.hour, 30, 0, 0, time.UTC)
+		bus := newRTCBus(t, now)
+
+		// 0x67 只读时间：时、分、秒
+		bus.begin(0x67)
+		bus.expect("time (24h)", bus.readBytes(3), []uint8{tt.want24, 0x30, 0x00})
+		bus.end()
+
+		bus.begin(0x62)
+		bus.writeByte(0x00)
+		bus.end()
+
+		bus.begin(0x67)
+		bus.expect("time (12h)", bus.readBytes(3), []uint8{tt.want12, 0x30, 0x00})
+		bus.end()
+	}
+}
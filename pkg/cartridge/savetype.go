@@ -0,0 +1,205 @@
+package cartridge
+
+import (
+	"bytes"
+	"fmt"
+	"gba/pkg/backup"
+)
+
+type SaveType int
+
+const (
+	SaveNone SaveType = iota
+	SaveSRAM
+	SaveFlash64K
+	SaveFlash128K
+	// EEPROM_V 无法区分容量，由 DMA 长度在运行时识别
+	SaveEEPROM
+	SaveEEPROM512
+	SaveEEPROM8K
+)
+
+func (t SaveType) Size() int {
+	switch t {
+	case SaveSRAM:
+		return 0x8000
+	case SaveFlash64K:
+		return 0x10000
+	case SaveFlash128K:
+		return 0x20000
+	case SaveEEPROM512:
+		return 0x200
+	case SaveEEPROM8K:
+		return 0x2000
+	default:
+		return 0
+	}
+}
+
+func (t SaveType) String() string {
+	switch t {
+	case SaveNone:
+		return "None"
+	case SaveSRAM:
+		return "SRAM 32K"
+	case SaveFlash64K:
+		return "Flash 64K"
+	case SaveFlash128K:
+		return "Flash 128K"
+	case SaveEEPROM:
+		return "EEPROM"
+	case SaveEEPROM512:
+		return "EEPROM 512B"
+	case SaveEEPROM8K:
+		return "EEPROM 8K"
+	default:
+		return fmt.Sprintf("SaveType(%d)", int(t))
+	}
+}
+
+// 任天堂存档库在 ROM 中留下的版本字符串
+var saveLibraryIDs = []struct {
+	marker   string
+	saveType SaveType
+}{
+	{"EEPROM_V", SaveEEPROM},
+	{"SRAM_V", SaveSRAM},
+	{"SRAM_F_V", SaveSRAM},
+	{"FLASH_V", SaveFlash64K},
+	{"FLASH512_V", SaveFlash64K},
+	{"FLASH1M_V", SaveFlash128K},
+}
+
+// Hardware 是卡带上除存档芯片外的额外硬件
+type Hardware int
+
+const (
+	HardwareRTC Hardware = 1 << iota
+	HardwareLight
+	HardwareGyro
+	HardwareRumble
+	HardwareTilt
+)
+
+type Override struct {
+	SaveType SaveType
+	Hardware Hardware
+	// FlashID 为零值时使用容量对应的默认芯片
+	FlashID backup.FlashID
+}
+
+// 库字符串缺失或与实际芯片不符、或带有额外硬件的游戏，按游戏代码覆盖
+var overrides = map[string]Override{
+	// Iridion II 检测到存档芯片会进入保护流程
+	"AI2E": {SaveType: SaveNone},
+	"AI2P": {SaveType: SaveNone},
+	// Top Gun - Combat Zones
+	"A2YE": {SaveType: SaveNone},
+
+	// Dragon Ball Z - The Legacy of Goku II
+	"ALFE": {SaveType: SaveEEPROM8K},
+	"ALFJ": {SaveType: SaveEEPROM8K},
+	"ALFP": {SaveType: SaveEEPROM8K},
+
+	// Super Mario Advance 4
+	"AX4E": {SaveType: SaveFlash128K},
+	"AX4J": {SaveType: SaveFlash128K},
+	"AX4P": {SaveType: SaveFlash128K},
+
+	// Pokémon Ruby
+	"AXVJ": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXVE": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXVP": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXVI": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXVS": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXVD": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXVF": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+
+	// Pokémon Sapphire
+	"AXPJ": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXPE": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXPP": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXPI": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXPS": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXPD": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"AXPF": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+
+	// Pokémon Emerald
+	"BPEJ": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"BPEE": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"BPEP": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"BPEI": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"BPES": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"BPED": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+	"BPEF": {SaveType: SaveFlash128K, Hardware: HardwareRTC, FlashID: backup.FlashMacronix128K},
+
+	// Boktai - The Sun Is in Your Hand
+	"U3IJ": {SaveType: SaveEEPROM, Hardware: HardwareRTC | HardwareLight},
+	"U3IE": {SaveType: SaveEEPROM, Hardware: HardwareRTC | HardwareLight},
+	"U3IP": {SaveType: SaveEEPROM, Hardware: HardwareRTC | HardwareLight},
+
+	// Boktai 2 - Solar Boy Django
+	"U32J": {SaveType: SaveEEPROM, Hardware: HardwareRTC | HardwareLight},
+	"U32E": {SaveType: SaveEEPROM, Hardware: HardwareRTC | HardwareLight},
+	"U32P": {SaveType: SaveEEPROM, Hardware: HardwareRTC | HardwareLight},
+
+	// Sennen Kazoku
+	"BKAJ": {SaveType: SaveFlash128K, Hardware: HardwareRTC},
+
+	// Rockman EXE 4.5 - Real Operation
+	"BR4J": {SaveType: SaveFlash128K, Hardware: HardwareRTC},
+
+	// Drill Dozer
+	"V49J": {SaveType: SaveSRAM, Hardware: HardwareRumble},
+	"V49E": {SaveType: SaveSRAM, Hardware: HardwareRumble},
+	"V49P": {SaveType: SaveSRAM, Hardware: HardwareRumble},
+
+	// WarioWare Twisted
+	"RZWJ": {SaveType: SaveSRAM, Hardware: HardwareGyro | HardwareRumble},
+	"RZWE": {SaveType: SaveSRAM, Hardware: HardwareGyro | HardwareRumble},
+	"RZWP": {SaveType: SaveSRAM, Hardware: HardwareGyro | HardwareRumble},
+
+	// Yoshi Topsy-Turvy
+	"KYGE": {SaveType: SaveEEPROM8K, Hardware: HardwareTilt},
+	"KYGJ": {SaveType: SaveEEPROM8K, Hardware: HardwareTilt},
+	"KYGP": {SaveType: SaveEEPROM8K, Hardware: HardwareTilt},
+
+	// Koro Koro Puzzle - Happy Panechu!
+	"KHPJ": {SaveType: SaveEEPROM, Hardware: HardwareTilt},
+}
+
+func (c *Cartridge) detectSaveType() SaveType {
+	if override, ok := overrides[c.GameCode]; ok {
+		return override.SaveType
+	}
+
+	// 库字符串总是 4 字节对齐
+	for _, id := range saveLibraryIDs {
+		marker := []byte(id.marker)
+		for offset := 0; offset < len(c.ROM); {
+			idx := bytes.Index(c.ROM[offset:], marker)
+			if idx < 0 {
+				break
+			}
+			if (offset+idx)%4 == 0 {
+				return id.saveType
+			}
+			offset += idx + 1
+		}
+	}
+
+	return SaveNone
+}
+
+func (c *Cartridge) GetSaveType() SaveType {
+	return c.saveType
+}
+
+// FlashID 返回覆盖表为这张卡带指定的 Flash 芯片 ID，没有指定时 ok 为 false
+func (c *Cartridge) FlashID() (id backup.FlashID, ok bool) {
+	override, found := overrides[c.GameCode]
+	if !found || override.FlashID == (backup.FlashID{}) {
+		return backup.FlashID{}, false
+	}
+	return override.FlashID, true
+}
@@ -0,0 +1,62 @@
+package cartridge
+
+import (
+	"testing"
+
+	"gba/pkg/backup"
+)
+
+// testROM 生成带游戏代码的 ROM，并在 offset 处写入库字符串
+func testROM(gameCode, marker string, offset int) *Cartridge {
+	rom := make([]byte, 0x1000)
+	copy(rom[0xAC:], gameCode)
+	if marker != "" {
+		copy(rom[offset:], marker)
+	}
+
+	c := &Cartridge{ROM: rom}
+	c.parseHeader()
+	return c
+}
+
+func TestDetectSaveType(t *testing.T) {
+	tests := []struct {
+		name     string
+		gameCode string
+		marker   string
+		offset   int
+		want     SaveType
+		wantSize int
+	}{
+		{"SRAM", "ATST", "SRAM_V113", 0x400, SaveSRAM, 0x8000},
+		{"SRAM_F", "ATST", "SRAM_F_V100", 0x400, SaveSRAM, 0x8000},
+		{"Flash", "ATST", "FLASH_V126", 0x400, SaveFlash64K, 0x10000},
+		{"Flash 512", "ATST", "FLASH512_V131", 0x400, SaveFlash64K, 0x10000},
+		{"Flash 1M", "ATST", "FLASH1M_V103", 0x400, SaveFlash128K, 0x20000},
+		{"EEPROM", "ATST", "EEPROM_V124", 0x400, SaveEEPROM, 0},
+		{"unaligned marker", "ATST", "SRAM_V113", 0x401, SaveNone, 0},
+		{"no marker", "ATST", "", 0, SaveNone, 0},
+		// 库字符串写着 Flash 64K，实际是 128K 芯片
+		{"override", "AX4E", "FLASH512_V131", 0x400, SaveFlash128K, 0x20000},
+	}
+
+	for _, tt := range tests {
+		c := testROM(tt.gameCode, tt.marker, tt.offset)
+		got := c.detectSaveType()
+		if got != tt.want {
+			t.Errorf("%s: detectSaveType() = %v, want %v", tt.name, got, tt.want)
+		}
+		if got.Size() != tt.wantSize {
+			t.Errorf("%s: Size() = %#x, want %#x", tt.name, got.Size(), tt.wantSize)
+		}
+	}
+}
+
+func TestFlashIDOverride(t *testing.T) {
+	if id, ok := testROM("BPEE", "FLASH1M_V103", 0x400).FlashID(); !ok || id != backup.FlashMacronix128K {
+		t.Errorf("BPEE: FlashID() = %v, %v, want %v, true", id, ok, backup.FlashMacronix128K)
+	}
+	if _, ok := testROM("ATST", "FLASH1M_V103", 0x400).FlashID(); ok {
+		t.Error("ATST: FlashID() reported an override")
+	}
+}
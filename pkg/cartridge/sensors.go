@@ -0,0 +1,202 @@
+package cartridge
+
+// 传感器数据由前端或测试脚本通过以下接口提供
+
+// LightSensor 返回环境亮度，0 为全暗，255 为最亮
+type LightSensor interface {
+	ReadLuminance() uint8
+}
+
+// Gyroscope 返回绕 Z 轴的角速度，以 int32 满量程表示
+type Gyroscope interface {
+	ReadGyroZ() int32
+}
+
+// TiltSensor 返回 X/Y 方向的倾斜量，以 int32 满量程表示
+type TiltSensor interface {
+	ReadTilt() (x, y int32)
+}
+
+// Rumble 接收卡带震动马达的开关状态
+type Rumble interface {
+	SetRumble(on bool)
+}
+
+type LightSensorFunc func() uint8
+
+func (f LightSensorFunc) ReadLuminance() uint8 {
+	return f()
+}
+
+type GyroscopeFunc func() int32
+
+func (f GyroscopeFunc) ReadGyroZ() int32 {
+	return f()
+}
+
+type TiltSensorFunc func() (x, y int32)
+
+func (f TiltSensorFunc) ReadTilt() (x, y int32) {
+	return f()
+}
+
+type RumbleFunc func(on bool)
+
+func (f RumbleFunc) SetRumble(on bool) {
+	f(on)
+}
+
+// SolarSensor 是 Boktai 的光传感器
+// 引脚: bit0 = 时钟, bit1 = 复位, bit2 = 片选（低有效）, bit3 = 计数到达标志
+type SolarSensor struct {
+	Source LightSensor
+
+	counter int
+	sample  int
+	edge    bool
+	output  uint8
+}
+
+func NewSolarSensor() *SolarSensor {
+	return &SolarSensor{sample: 0xFF}
+}
+
+func (s *SolarSensor) ReadPins() uint8 {
+	return s.output
+}
+
+func (s *SolarSensor) WritePins(pins, direction uint8) {
+	if pins&0x4 != 0 {
+		return
+	}
+
+	if pins&0x2 != 0 {
+		// 复位时采样亮度，越亮计数越早到达
+		s.counter = 0
+		s.sample = 0xFF
+		if s.Source != nil {
+			s.sample = 0xFF - int(s.Source.ReadLuminance())
+		}
+	}
+
+	if pins&0x1 != 0 && s.edge {
+		s.counter++
+	}
+	s.edge = pins&0x1 == 0
+
+	s.output = 0
+	if s.counter >= s.sample {
+		s.output = 0x8
+	}
+}
+
+// Gyro 是 WarioWare Twisted 的陀螺仪
+// 引脚: bit0 = 锁存采样, bit1 = 时钟, bit2 = 串行数据输出
+type Gyro struct {
+	Source Gyroscope
+
+	sample uint16
+	edge   bool
+	output uint8
+}
+
+func NewGyro() *Gyro {
+	return &Gyro{}
+}
+
+func (g *Gyro) ReadPins() uint8 {
+	return g.output
+}
+
+func (g *Gyro) WritePins(pins, direction uint8) {
+	if pins&0x1 != 0 {
+		// 归一化到约 12 位，中心为 0x6C0，没有数据源时保持静止
+		var z int32
+		if g.Source != nil {
+			z = g.Source.ReadGyroZ()
+		}
+		g.sample = uint16((z >> 21) + 0x6C0)
+	}
+
+	// 时钟下降沿移出一位
+	if g.edge && pins&0x2 == 0 {
+		g.output = uint8(g.sample>>15) << 2
+		g.sample <<= 1
+	}
+	g.edge = pins&0x2 != 0
+}
+
+// RumbleMotor 是 Drill Dozer / WarioWare Twisted 的震动马达，接在 bit3
+type RumbleMotor struct {
+	Motor Rumble
+}
+
+func NewRumbleMotor() *RumbleMotor {
+	return &RumbleMotor{}
+}
+
+func (r *RumbleMotor) ReadPins() uint8 {
+	return 0
+}
+
+func (r *RumbleMotor) WritePins(pins, direction uint8) {
+	if r.Motor != nil && direction&0x8 != 0 {
+		r.Motor.SetRumble(pins&0x8 != 0)
+	}
+}
+
+// Tilt 是 Yoshi Topsy-Turvy / Koro Koro Puzzle 的倾斜传感器
+// 映射在 SRAM 区域 0x0E008000-0x0E0085FF
+type Tilt struct {
+	Source TiltSensor
+
+	armed bool
+	x     uint16
+	y     uint16
+}
+
+const (
+	TiltStart = 0x8000
+	TiltEnd   = 0x8600
+)
+
+func NewTilt() *Tilt {
+	return &Tilt{x: 0x3A0, y: 0x3A0}
+}
+
+func IsTiltAddress(offset uint32) bool {
+	return offset >= TiltStart && offset < TiltEnd
+}
+
+func (t *Tilt) Write8(offset uint32, val uint8) {
+	switch offset {
+	case 0x8000:
+		t.armed = val == 0x55
+	case 0x8100:
+		if val == 0xAA && t.armed {
+			t.armed = false
+			if t.Source != nil {
+				x, y := t.Source.ReadTilt()
+				// 归一化到约 12 位，中心为 0x3A0
+				t.x = uint16((x >> 21) + 0x3A0)
+				t.y = uint16((y >> 21) + 0x3A0)
+			}
+		}
+	}
+}
+
+func (t *Tilt) Read8(offset uint32) uint8 {
+	switch offset {
+	case 0x8200:
+		return uint8(t.x)
+	case 0x8300:
+		// bit 7 表示采样完成
+		return uint8((t.x>>8)&0xF) | 0x80
+	case 0x8400:
+		return uint8(t.y)
+	case 0x8500:
+		return uint8((t.y >> 8) & 0xF)
+	default:
+		return 0
+	}
+}
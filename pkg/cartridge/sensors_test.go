@@ -0,0 +1,145 @@
+package cartridge_test
+
+import (
+	"testing"
+
+	"gba/pkg/cartridge"
+	"gba/pkg/mmu"
+)
+
+func newGPIO(direction uint8, devices ...cartridge.GPIODevice) *cartridge.GPIO {
+	gpio := cartridge.NewGPIO()
+	for _, d := range devices {
+		gpio.Attach(d)
+	}
+	gpio.Write8(cartridge.GPIOControl, 1)
+	gpio.Write8(cartridge.GPIODirection, direction)
+	return gpio
+}
+
+func TestSolarSensor(t *testing.T) {
+	tests := []struct {
+		luminance uint8
+		want      int
+	}{
+		{0x00, 0xFF},
+		{0x40, 0xBF},
+		{0xE8, 0x17},
+		{0xFF, 0x00},
+	}
+
+	for _, tt := range tests {
+		solar := cartridge.NewSolarSensor()
+		lum := tt.luminance
+		solar.Source = cartridge.LightSensorFunc(func() uint8 { return lum })
+		gpio := newGPIO(0x7, solar)
+
+		// 片选为低，复位时采样亮度，然后数时钟脉冲直到 bit3 置位
+		gpio.Write8(cartridge.GPIOData, 0x2)
+		gpio.Write8(cartridge.GPIOData, 0x0)
+
+		pulses := 0
+		for gpio.Read8(cartridge.GPIOData)&0x8 == 0 && pulses < 0x100 {
+			gpio.Write8(cartridge.GPIOData, 0x1)
+			gpio.Write8(cartridge.GPIOData, 0x0)
+			pulses++
+		}
+		if pulses != tt.want {
+			t.Errorf("luminance %02X: flag after %d pulses, want %d", tt.luminance, pulses, tt.want)
+		}
+	}
+}
+
+// readGyro 锁存一次采样，再在 16 个时钟下降沿上从 bit2 读出数据，高位在前
+func readGyro(gpio *cartridge.GPIO) uint16 {
+	gpio.Write8(cartridge.GPIOData, 0x1)
+	gpio.Write8(cartridge.GPIOData, 0x0)
+
+	var val uint16
+	for i := 0; i < 16; i++ {
+		gpio.Write8(cartridge.GPIOData, 0x2)
+		gpio.Write8(cartridge.GPIOData, 0x0)
+		val = val<<1 | uint16(gpio.Read8(cartridge.GPIOData)>>2&1)
+	}
+	return val
+}
+
+func TestGyro(t *testing.T) {
+	tests := []struct {
+		z    int32
+		want uint16
+	}{
+		{0, 0x6C0},
+		{0x10000000, 0x740},
+		{-0x20000000, 0x5C0},
+	}
+
+	for _, tt := range tests {
+		gyro := cartridge.NewGyro()
+		z := tt.z
+		gyro.Source = cartridge.GyroscopeFunc(func() int32 { return z })
+		gpio := newGPIO(0xB, gyro)
+
+		if got := readGyro(gpio); got != tt.want {
+			t.Errorf("z %d: sample = %03X, want %03X", tt.z, got, tt.want)
+		}
+	}
+
+	// 没有数据源时同样跟踪时钟沿，读出静止时的中心值
+	gpio := newGPIO(0xB, cartridge.NewGyro())
+	if got := readGyro(gpio); got != 0x6C0 {
+		t.Errorf("no source: sample = %03X, want 6C0", got)
+	}
+}
+
+func TestRumble(t *testing.T) {
+	var states []bool
+	rumble := cartridge.NewRumbleMotor()
+	rumble.Motor = cartridge.RumbleFunc(func(on bool) { states = append(states, on) })
+
+	// bit3 设为输入时马达不受数据位影响
+	gpio := newGPIO(0x3, rumble)
+	gpio.Write8(cartridge.GPIOData, 0x8)
+	if len(states) != 0 {
+		t.Fatalf("rumble changed while bit3 is an input: %v", states)
+	}
+
+	gpio.Write8(cartridge.GPIODirection, 0xB)
+	gpio.Write8(cartridge.GPIOData, 0x8)
+	gpio.Write8(cartridge.GPIOData, 0x0)
+	if len(states) != 2 || !states[0] || states[1] {
+		t.Errorf("rumble states = %v, want [true false]", states)
+	}
+}
+
+func TestTilt(t *testing.T) {
+	tilt := cartridge.NewTilt()
+	tilt.Source = cartridge.TiltSensorFunc(func() (int32, int32) { return 0x10000000, -0x20000000 })
+
+	m := mmu.New()
+	m.Tilt = tilt
+
+	// 采样前返回中心值
+	if got := m.Read8(0x0E008200); got != 0xA0 {
+		t.Errorf("x low before sample = %02X, want A0", got)
+	}
+
+	m.Write8(0x0E008000, 0x55)
+	m.Write8(0x0E008100, 0xAA)
+
+	x := uint16(m.Read8(0x0E008200)) | uint16(m.Read8(0x0E008300)&0xF)<<8
+	y := uint16(m.Read8(0x0E008400)) | uint16(m.Read8(0x0E008500)&0xF)<<8
+	if x != 0x420 || y != 0x2A0 {
+		t.Errorf("tilt = %03X/%03X, want 420/2A0", x, y)
+	}
+	if m.Read8(0x0E008300)&0x80 == 0 {
+		t.Error("sample ready bit not set")
+	}
+
+	// 没有先写 0x55 时不会重新采样
+	tilt.Source = cartridge.TiltSensorFunc(func() (int32, int32) { return 0, 0 })
+	m.Write8(0x0E008100, 0xAA)
+	if got := m.Read8(0x0E008200); got != 0x20 {
+		t.Errorf("x low after unarmed write = %02X, want 20", got)
+	}
+}
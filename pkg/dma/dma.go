@@ -7,6 +7,14 @@ const (
 	DMA3CNT_H = 0x040000DE
 )
 
+// CNT_H 第 12-13 位的启动时机
+const (
+	StartImmediate = 0
+	StartVBlank    = 1
+	StartHBlank    = 2
+	StartSpecial   = 3
+)
+
 type DMA struct {
 	SAD   [4]uint32
 	DAD   [4]uint32
@@ -99,7 +107,7 @@ func (d *DMA) startTransfer(channel int) {
 
 	startTiming := (d.CNT_H[channel] >> 12) & 0x3
 
-	if startTiming == 0 {
+	if startTiming == StartImmediate {
 		d.Active[channel] = true
 	}
 }
@@ -157,6 +165,9 @@ func (d *DMA) transfer(channel int) int {
 
 		if d.CNT_H[channel]&0x0200 != 0 {
 			d.startTransfer(channel)
+		} else {
+			// 非重复传输完成后清除使能位，允许游戏再次启动
+			d.CNT_H[channel] &^= 0x8000
 		}
 	}
 
@@ -182,11 +193,11 @@ func (d *DMA) adjustAddress(addr uint32, control uint16, transferType uint16) ui
 	return addr
 }
 
+// Trigger 启动所有等待该时机的通道，地址和长度已在使能或重复时锁存
 func (d *DMA) Trigger(startTiming int) {
 	for i := 0; i < 4; i++ {
 		timing := int((d.CNT_H[i] >> 12) & 0x3)
 		if timing == startTiming && d.CNT_H[i]&0x8000 != 0 {
-			d.startTransfer(i)
 			d.Active[i] = true
 		}
 	}
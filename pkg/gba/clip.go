@@ -0,0 +1,69 @@
+package gba
+
+import (
+	"fmt"
+	"gba/pkg/capture"
+	"gba/pkg/ppu"
+)
+
+const (
+	// 默认保留最近 5 秒画面
+	DefaultClipSeconds = 5
+	// GIF 延时精度为 1/100 秒，每两帧保存一帧（约 30fps）
+	clipFrameStep = 2
+)
+
+// SetClipSeconds 设置 GIF 片段保留的时长，0 表示关闭
+func (g *GBA) SetClipSeconds(seconds float64) {
+	g.recordMu.Lock()
+	defer g.recordMu.Unlock()
+
+	if seconds <= 0 {
+		g.clip = nil
+		return
+	}
+	g.clip = capture.NewFrameRing(seconds, clipFrameStep)
+}
+
+// Clip 是 GIF 片段缓冲在某一时刻的副本，不再依赖模拟器状态，可以在后台编码
+type Clip struct {
+	Frames  [][]uint16
+	Step    int
+	Profile ppu.ColorProfile
+}
+
+// SnapshotClip 复制最近的画面和当前的颜色校正方案，可在 GUI 线程调用
+func (g *GBA) SnapshotClip() (*Clip, error) {
+	g.recordMu.Lock()
+	ring := g.clip
+	profile := g.PPU.ColorProfile()
+	g.recordMu.Unlock()
+
+	if ring == nil {
+		return nil, fmt.Errorf("clip buffer is disabled")
+	}
+
+	frames := ring.Snapshot()
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames captured yet")
+	}
+	return &Clip{Frames: frames, Step: ring.Step, Profile: profile}, nil
+}
+
+// Save 把片段保存为 GIF，scale 为整数放大倍数
+func (c *Clip) Save(path string, scale int) error {
+	if err := capture.WriteGIF(path, c.Frames, c.Step, scale, c.Profile); err != nil {
+		return err
+	}
+	fmt.Printf("[GBA] Saved %d frame clip to %s\n", len(c.Frames), path)
+	return nil
+}
+
+// SaveClip 把最近的画面保存为 GIF，可在 GUI 线程调用
+func (g *GBA) SaveClip(path string, scale int) error {
+	clip, err := g.SnapshotClip()
+	if err != nil {
+		return err
+	}
+	return clip.Save(path, scale)
+}
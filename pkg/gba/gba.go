@@ -3,6 +3,7 @@ package gba
 import (
 	"fmt"
 	"gba/pkg/apu"
+	"gba/pkg/capture"
 	"gba/pkg/cartridge"
 	"gba/pkg/cpu"
 	"gba/pkg/dma"
@@ -10,6 +11,8 @@ import (
 	"gba/pkg/mmu"
 	"gba/pkg/ppu"
 	"gba/pkg/timer"
+	"os"
+	"sync"
 )
 
 const (
@@ -28,8 +31,19 @@ type GBA struct {
 
 	Cartridge *cartridge.Cartridge
 
+	// SaveDir 为空时存档放在 ROM 所在目录
+	SaveDir  string
+	SavePath string
+
 	FrameCount  int
 	TotalCycles int64
+
+	lastSaveFlush int
+
+	// 录像和 GIF 片段可以由 GUI 线程开始和停止，因此用锁保护
+	recordMu sync.Mutex
+	recorder *capture.Recorder
+	clip     *capture.FrameRing
 }
 
 func New() *GBA {
@@ -37,7 +51,7 @@ func New() *GBA {
 
 	gba.MMU = mmu.New()
 	gba.CPU = cpu.New()
-	gba.PPU = ppu.New(gba.MMU.GetVRAM(), gba.MMU.GetPalette(), gba.MMU.GetOAM())
+	gba.PPU = ppu.New(gba.MMU.GetVRAM(), gba.MMU.GetPalette(), gba.MMU.GetOAM(), gba.RequestInterrupt)
 	gba.APU = apu.New()
 	gba.DMA = dma.New(
 		gba.MMU.Read32,
@@ -50,6 +64,7 @@ func New() *GBA {
 	gba.Input = input.New()
 
 	gba.setupCallbacks()
+	gba.SetClipSeconds(DefaultClipSeconds)
 
 	return gba
 }
@@ -61,6 +76,12 @@ func (g *GBA) setupCallbacks() {
 	g.CPU.Write8 = g.MMU.Write8
 	g.CPU.Write16 = g.MMU.Write16
 	g.CPU.Write32 = g.MMU.Write32
+
+	g.MMU.PPU = g.PPU
+	g.MMU.APU = g.APU
+	g.MMU.DMA = g.DMA
+	g.PPU.OnHBlank = func() { g.DMA.Trigger(dma.StartHBlank) }
+	g.PPU.OnVBlank = func() { g.DMA.Trigger(dma.StartVBlank) }
 }
 
 func (g *GBA) Reset() {
@@ -112,6 +133,13 @@ func (g *GBA) Reset() {
 
 	g.FrameCount = 0
 	g.TotalCycles = 0
+	g.lastSaveFlush = 0
+
+	g.recordMu.Lock()
+	if g.clip != nil {
+		g.clip.Clear()
+	}
+	g.recordMu.Unlock()
 }
 
 func (g *GBA) LoadROM(filename string) error {
@@ -123,30 +151,44 @@ func (g *GBA) LoadROM(filename string) error {
 		return fmt.Errorf("failed to load cartridge: %w", err)
 	}
 
+	// 切换游戏前先保存上一个游戏的进度
+	if err := g.FlushSave(); err != nil {
+		fmt.Printf("[GBA] ERROR: %v\n", err)
+	}
+
 	g.Cartridge = cart
 	fmt.Printf("[GBA] Cartridge loaded, ROM size: %d bytes\n", len(cart.GetROM()))
 
 	g.MMU.LoadROM(cart.GetROM())
+	g.MMU.SetBackup(newBackup(cart))
+	g.MMU.GPIO = cart.GPIO
+	g.MMU.Tilt = cart.Tilt
 	fmt.Printf("[GBA] ROM loaded into MMU\n")
 
 	g.Reset()
 	fmt.Printf("[GBA] System reset complete\n")
+
+	g.SavePath = g.savePathFor(filename)
+	if err := g.loadSave(); err != nil {
+		fmt.Printf("[GBA] ERROR: %v\n", err)
+	}
 	fmt.Printf("[GBA] Initial PC: 0x%08X, CPSR: 0x%08X\n", g.CPU.PC, g.CPU.CPSR)
 
 	return nil
 }
 
+// LoadBIOS 直接读取 BIOS 文件，不经过卡带的存档类型和 GPIO 检测
 func (g *GBA) LoadBIOS(filename string) error {
-	data, err := cartridge.Load(filename)
+	data, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to load BIOS: %w", err)
 	}
 
-	if len(data.GetROM()) != 0x4000 {
+	if len(data) != 0x4000 {
 		return fmt.Errorf("BIOS must be exactly 16KB")
 	}
 
-	g.MMU.LoadBIOS(data.GetROM())
+	g.MMU.LoadBIOS(data)
 	return nil
 }
 
@@ -161,9 +203,7 @@ func (g *GBA) Step() int {
 
 	if g.PPU.Step(cycles) {
 		g.FrameCount++
-		if g.MMU.CheckInterrupts() {
-			g.handleInterrupt()
-		}
+		g.recordFrame()
 	}
 
 	if g.MMU.CheckInterrupts() {
@@ -181,6 +221,8 @@ func (g *GBA) RunFrame() {
 		cycles += g.Step()
 	}
 
+	g.autoFlushSave()
+
 	// 每 60 帧（约 1 秒）输出一次日志
 	if g.FrameCount%60 == 0 && g.FrameCount != frameStart {
 		fmt.Printf("[GBA] Frame %d, PC: 0x%08X, Total Cycles: %d\n",
@@ -202,8 +244,6 @@ func (g *GBA) handleInterrupt() {
 		return
 	}
 
-	fmt.Printf("[GBA] INTERRUPT! PC=0x%08X -> 0x00000018, IRQ=0x%04X\n", g.CPU.PC, irq)
-
 	g.CPU.SaveMode()
 	g.CPU.SwitchMode(cpu.ModeIRQ)
 	g.CPU.SetSPSR(g.CPU.CPSR)
@@ -221,6 +261,24 @@ func (g *GBA) GetFrameBuffer() []uint16 {
 	return g.PPU.GetFrameBuffer()
 }
 
+// CopyFrame 把最近一帧完整画面复制到 dst，可在 GUI 线程调用，返回帧序号
+func (g *GBA) CopyFrame(dst []uint16) uint64 {
+	return g.PPU.CopyFrame(dst)
+}
+
+// AcquireFrame 取得最近一帧完整画面，用完后必须调用 ReleaseFrame
+func (g *GBA) AcquireFrame() ([]uint16, uint64) {
+	return g.PPU.AcquireFrame()
+}
+
+func (g *GBA) ReleaseFrame() {
+	g.PPU.ReleaseFrame()
+}
+
+func (g *GBA) FrameSeq() uint64 {
+	return g.PPU.FrameSeq()
+}
+
 func (g *GBA) SetKey(key int, pressed bool) {
 	g.Input.SetKey(key, pressed)
 	g.MMU.KEYINPUT = g.Input.GetKEYINPUT()
@@ -0,0 +1,80 @@
+package gba
+
+import (
+	"fmt"
+	"gba/pkg/capture"
+)
+
+// StartRecording 开始录像，格式由扩展名决定（.avi 或 .y4m + .wav），可在 GUI 线程调用
+func (g *GBA) StartRecording(path string) error {
+	g.recordMu.Lock()
+	defer g.recordMu.Unlock()
+
+	if g.recorder != nil {
+		return fmt.Errorf("already recording to %s", g.recorder.Path)
+	}
+
+	rec, err := capture.NewRecorder(path, g.PPU.ColorProfile())
+	if err != nil {
+		return err
+	}
+	g.recorder = rec
+	// 开始录像前积累的样本不属于录像，第一帧只写入从现在起生成的声音
+	g.APU.ClearBuffer()
+	fmt.Printf("[GBA] Recording started: %s\n", path)
+	return nil
+}
+
+// StopRecording 结束录像并补全文件头，没有在录像时什么也不做
+func (g *GBA) StopRecording() error {
+	g.recordMu.Lock()
+	defer g.recordMu.Unlock()
+	return g.stopRecordingLocked()
+}
+
+func (g *GBA) stopRecordingLocked() error {
+	if g.recorder == nil {
+		return nil
+	}
+
+	rec := g.recorder
+	g.recorder = nil
+	if err := rec.Close(); err != nil {
+		return fmt.Errorf("failed to finish recording: %w", err)
+	}
+	fmt.Printf("[GBA] Recording stopped: %d frames written to %v\n", rec.Frames(), rec.Files())
+	return nil
+}
+
+func (g *GBA) IsRecording() bool {
+	g.recordMu.Lock()
+	defer g.recordMu.Unlock()
+	return g.recorder != nil
+}
+
+// recordFrame 在每帧进入 VBlank 时写入刚画完的画面和这一帧产生的声音，并记入 GIF 片段缓冲
+func (g *GBA) recordFrame() {
+	g.recordMu.Lock()
+	defer g.recordMu.Unlock()
+
+	if g.clip != nil {
+		g.clip.Push(g.PPU.GetFrameBuffer())
+	}
+
+	if g.recorder == nil {
+		return
+	}
+
+	err := g.recorder.WriteFrame(g.PPU.GetFrameBuffer())
+	if err == nil {
+		err = g.recorder.WriteAudio(g.APU.GetSamples())
+	}
+	g.APU.ClearBuffer()
+
+	if err != nil {
+		fmt.Printf("[GBA] ERROR: Recording failed: %v\n", err)
+		if err := g.stopRecordingLocked(); err != nil {
+			fmt.Printf("[GBA] ERROR: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package gba
+
+import (
+	"fmt"
+	"gba/pkg/backup"
+	"gba/pkg/cartridge"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 存档脏数据至少间隔这么多帧才落盘一次，避免游戏连续写入时频繁写文件
+const SaveFlushInterval = 60
+
+// newBackup 根据卡带的存档类型创建对应的存档芯片，没有存档芯片时返回 nil
+func newBackup(cart *cartridge.Cartridge) backup.Device {
+	saveType := cart.GetSaveType()
+	fmt.Printf("[GBA] Backup: %s\n", saveType)
+
+	switch saveType {
+	case cartridge.SaveSRAM:
+		return backup.NewSRAM()
+	case cartridge.SaveFlash64K, cartridge.SaveFlash128K:
+		flash := backup.NewFlash(saveType.Size())
+		if id, ok := cart.FlashID(); ok {
+			flash.ID = id
+		}
+		fmt.Printf("[GBA] Flash ID: %02X%02X\n", flash.ID.Maker, flash.ID.Device)
+		return flash
+	case cartridge.SaveEEPROM, cartridge.SaveEEPROM512, cartridge.SaveEEPROM8K:
+		return backup.NewEEPROM(saveType.Size())
+	default:
+		return nil
+	}
+}
+
+// savePathFor 返回 ROM 对应的 .sav 路径，SaveDir 为空时放在 ROM 旁边
+func (g *GBA) savePathFor(romFile string) string {
+	base := filepath.Base(romFile)
+	name := strings.TrimSuffix(base, filepath.Ext(base)) + ".sav"
+
+	if g.SaveDir != "" {
+		return filepath.Join(g.SaveDir, name)
+	}
+	return filepath.Join(filepath.Dir(romFile), name)
+}
+
+func (g *GBA) loadSave() error {
+	data, err := os.ReadFile(g.SavePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Printf("[GBA] No save file found at %s\n", g.SavePath)
+			return nil
+		}
+		return fmt.Errorf("failed to read save file: %w", err)
+	}
+
+	g.MMU.LoadSave(data)
+	fmt.Printf("[GBA] Loaded %d bytes of save data from %s\n", len(data), g.SavePath)
+	return nil
+}
+
+// FlushSave 将脏的存档数据写入磁盘，没有修改时什么也不做
+func (g *GBA) FlushSave() error {
+	if g.SavePath == "" || !g.MMU.IsSaveDirty() {
+		return nil
+	}
+
+	if dir := filepath.Dir(g.SavePath); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create save directory: %w", err)
+		}
+	}
+
+	if err := writeFileAtomic(g.SavePath, g.MMU.GetSaveData()); err != nil {
+		return fmt.Errorf("failed to write save file: %w", err)
+	}
+
+	g.MMU.ClearSaveDirty()
+	g.lastSaveFlush = g.FrameCount
+	fmt.Printf("[GBA] Save data written to %s\n", g.SavePath)
+	return nil
+}
+
+func (g *GBA) autoFlushSave() {
+	if !g.MMU.IsSaveDirty() || g.FrameCount-g.lastSaveFlush < SaveFlushInterval {
+		return
+	}
+
+	if err := g.FlushSave(); err != nil {
+		fmt.Printf("[GBA] ERROR: %v\n", err)
+		// 出错后同样等待一个周期再重试
+		g.lastSaveFlush = g.FrameCount
+	}
+}
+
+// writeFileAtomic 先写临时文件再重命名，保证崩溃时不会留下半个存档
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
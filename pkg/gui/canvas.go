@@ -3,9 +3,11 @@ package gui
 import (
 	"fmt"
 	"image"
-	"image/color"
+	"sync/atomic"
 
+	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
+	"gba/pkg/ppu"
 )
 
 const (
@@ -13,13 +15,28 @@ const (
 	ScreenHeight = 160
 )
 
+// FrameSource 提供已完成的帧，AcquireFrame 和 ReleaseFrame 之间画面归调用方所有
+type FrameSource interface {
+	AcquireFrame() ([]uint16, uint64)
+	ReleaseFrame()
+}
+
+// GameCanvas 在 Fyne 的绘制线程里从 FrameSource 拉取画面，模拟线程只负责调用 Refresh
 type GameCanvas struct {
-	image  *canvas.Image
-	pixels []uint8
+	raster *canvas.Raster
+	source FrameSource
 	scale  int
+
+	// 菜单在 UI 线程修改，绘制时读取
+	profile atomic.Int32
+
+	// 以下只在绘制线程访问
+	img          *image.RGBA
+	drawnSeq     uint64
+	drawnProfile ppu.ColorProfile
 }
 
-func NewGameCanvas(scale int) *GameCanvas {
+func NewGameCanvas(scale int, source FrameSource) *GameCanvas {
 	if scale < 1 {
 		scale = 1
 	}
@@ -31,31 +48,36 @@ func NewGameCanvas(scale int) *GameCanvas {
 		scale, ScreenWidth*scale, ScreenHeight*scale)
 
 	gc := &GameCanvas{
+		source: source,
 		scale:  scale,
-		pixels: make([]uint8, ScreenWidth*ScreenHeight*4),
+		img:    image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight)),
+		// 保证第一次绘制时转换
+		drawnSeq: ^uint64(0),
 	}
 
-	// 创建 Fyne 图像
-	gc.image = canvas.NewImageFromImage(gc.createImage())
-	gc.image.FillMode = canvas.ImageFillOriginal
-	gc.image.ScaleMode = canvas.ImageScalePixels
+	// 创建 Fyne 光栅对象，按像素放大到窗口尺寸
+	gc.raster = canvas.NewRaster(gc.draw)
+	gc.raster.ScaleMode = canvas.ImageScalePixels
+	gc.raster.SetMinSize(gc.size())
 
 	fmt.Printf("[Canvas] GameCanvas created successfully\n")
 
 	return gc
 }
 
-func (gc *GameCanvas) createImage() image.Image {
-	return image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight))
+func (gc *GameCanvas) Object() fyne.CanvasObject {
+	return gc.raster
 }
 
-func (gc *GameCanvas) Image() *canvas.Image {
-	return gc.image
+func (gc *GameCanvas) size() fyne.Size {
+	return fyne.NewSize(float32(ScreenWidth*gc.scale), float32(ScreenHeight*gc.scale))
 }
 
 func (gc *GameCanvas) SetScale(scale int) {
 	if scale >= 1 && scale <= 4 {
 		gc.scale = scale
+		gc.raster.SetMinSize(gc.size())
+		gc.raster.Refresh()
 	}
 }
 
@@ -63,95 +85,39 @@ func (gc *GameCanvas) GetScale() int {
 	return gc.scale
 }
 
-func (gc *GameCanvas) UpdateFrame(frameBuffer []uint16) {
-	if len(frameBuffer) != ScreenWidth*ScreenHeight {
-		fmt.Printf("[Canvas] ERROR: Invalid frame buffer size: %d (expected %d)\n",
-			len(frameBuffer), ScreenWidth*ScreenHeight)
-		return
-	}
-
-	// 检查帧缓冲是否全为0（黑屏）
-	allZero := true
-	for i := 0; i < 100 && i < len(frameBuffer); i++ {
-		if frameBuffer[i] != 0 {
-			allZero = false
-			break
-		}
-	}
-	if allZero {
-		fmt.Printf("[Canvas] WARNING: Frame buffer appears to be all zeros (black screen)\n")
-	}
-
-	// 将 RGB565 转换为 RGBA
-	for i, color16 := range frameBuffer {
-		r := uint8((color16 & 0x1F) << 3)
-		g := uint8(((color16 >> 5) & 0x1F) << 3)
-		b := uint8(((color16 >> 10) & 0x1F) << 3)
-
-		idx := i * 4
-		gc.pixels[idx] = r
-		gc.pixels[idx+1] = g
-		gc.pixels[idx+2] = b
-		gc.pixels[idx+3] = 255
-	}
-
-	// 创建新图像
-	img := image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight))
-	copy(img.Pix, gc.pixels)
-
-	// 如果缩放 > 1，进行缩放
-	if gc.scale > 1 {
-		img = gc.scaleImage(img, gc.scale)
-	}
+func (gc *GameCanvas) SetColorProfile(profile ppu.ColorProfile) {
+	gc.profile.Store(int32(profile))
+	gc.raster.Refresh()
+}
 
-	gc.image.Image = img
-	gc.image.Refresh()
+// Refresh 通知 Fyne 有新帧，可在任意线程调用，实际的转换在绘制线程进行
+func (gc *GameCanvas) Refresh() {
+	gc.raster.Refresh()
 }
 
-func (gc *GameCanvas) scaleImage(src *image.RGBA, scale int) *image.RGBA {
-	width := src.Bounds().Dx() * scale
-	height := src.Bounds().Dy() * scale
-
-	dst := image.NewRGBA(image.Rect(0, 0, width, height))
-
-	for y := 0; y < ScreenHeight; y++ {
-		for x := 0; x < ScreenWidth; x++ {
-			srcIdx := (y*ScreenWidth + x) * 4
-			r := gc.pixels[srcIdx]
-			g := gc.pixels[srcIdx+1]
-			b := gc.pixels[srcIdx+2]
-			a := gc.pixels[srcIdx+3]
-
-			for dy := 0; dy < scale; dy++ {
-				for dx := 0; dx < scale; dx++ {
-					dstX := x*scale + dx
-					dstY := y*scale + dy
-					dstIdx := (dstY*width + dstX) * 4
-					dst.Pix[dstIdx] = r
-					dst.Pix[dstIdx+1] = g
-					dst.Pix[dstIdx+2] = b
-					dst.Pix[dstIdx+3] = a
-				}
-			}
-		}
+// draw 是光栅的生成函数，由 Fyne 在绘制线程调用
+func (gc *GameCanvas) draw(w, h int) image.Image {
+	frame, seq := gc.source.AcquireFrame()
+	defer gc.source.ReleaseFrame()
+
+	profile := ppu.ColorProfile(gc.profile.Load())
+	if seq == gc.drawnSeq && profile == gc.drawnProfile {
+		return gc.img
 	}
+	gc.drawnSeq = seq
+	gc.drawnProfile = profile
 
-	return dst
-}
+	// 将 BGR555 按颜色校正方案转换为 RGBA
+	table := ppu.ColorTable(profile)
+	for i, color16 := range frame {
+		c := table[color16&0x7FFF]
 
-func (gc *GameCanvas) Clear() {
-	for i := range gc.pixels {
-		gc.pixels[i] = 0
+		idx := i * 4
+		gc.img.Pix[idx] = c.R
+		gc.img.Pix[idx+1] = c.G
+		gc.img.Pix[idx+2] = c.B
+		gc.img.Pix[idx+3] = 255
 	}
 
-	img := image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight))
-	gc.image.Image = img
-	gc.image.Refresh()
-}
-
-func rgb565ToRGBA(color16 uint16) color.RGBA {
-	r := uint8((color16 & 0x1F) << 3)
-	g := uint8(((color16 >> 5) & 0x1F) << 3)
-	b := uint8(((color16 >> 10) & 0x1F) << 3)
-	return color.RGBA{r, g, b, 255}
+	return gc.img
 }
@@ -11,12 +11,14 @@ import (
 type InputHandler struct {
 	emulator *gba.GBA
 	keys     map[fyne.KeyName]int
+	hotkeys  map[fyne.KeyName]func()
 }
 
 func NewInputHandler(emulator *gba.GBA) *InputHandler {
 	ih := &InputHandler{
 		emulator: emulator,
 		keys:     make(map[fyne.KeyName]int),
+		hotkeys:  make(map[fyne.KeyName]func()),
 	}
 
 	ih.setupKeyMap()
@@ -39,7 +41,16 @@ func (ih *InputHandler) setupKeyMap() {
 	ih.keys[fyne.KeyS] = input.KeyR
 }
 
+// SetHotkey 注册模拟器功能键（截图等），按下时调用 fn
+func (ih *InputHandler) SetHotkey(key fyne.KeyName, fn func()) {
+	ih.hotkeys[key] = fn
+}
+
 func (ih *InputHandler) HandleKeyDown(key *fyne.KeyEvent) {
+	if fn, ok := ih.hotkeys[key.Name]; ok {
+		fn()
+		return
+	}
 	if gbaKey, ok := ih.keys[key.Name]; ok {
 		ih.emulator.SetKey(gbaKey, true)
 	}
@@ -3,6 +3,7 @@ package gui
 import (
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/dialog"
+	"gba/pkg/ppu"
 )
 
 type MenuBar struct {
@@ -22,6 +23,22 @@ func (mb *MenuBar) Build() *fyne.MainMenu {
 		mb.window.ShowOpenFileDialog()
 	})
 
+	screenshotItem := fyne.NewMenuItem("Screenshot (F12)", func() {
+		mb.window.TakeScreenshot()
+	})
+
+	clipItem := fyne.NewMenuItem("Save GIF Clip (F10)", func() {
+		mb.window.SaveClip()
+	})
+
+	startRecordItem := fyne.NewMenuItem("Start Recording", func() {
+		mb.window.StartRecording()
+	})
+
+	stopRecordItem := fyne.NewMenuItem("Stop Recording", func() {
+		mb.window.StopRecording()
+	})
+
 	resetItem := fyne.NewMenuItem("Reset", func() {
 		mb.window.Reset()
 	})
@@ -30,7 +47,7 @@ func (mb *MenuBar) Build() *fyne.MainMenu {
 		mb.window.Quit()
 	})
 
-	fileMenu := fyne.NewMenu("File", openItem, fyne.NewMenuItemSeparator(), resetItem, fyne.NewMenuItemSeparator(), exitItem)
+	fileMenu := fyne.NewMenu("File", openItem, screenshotItem, clipItem, startRecordItem, stopRecordItem, fyne.NewMenuItemSeparator(), resetItem, fyne.NewMenuItemSeparator(), exitItem)
 
 	// View 菜单
 	scaleMenu := fyne.NewMenuItem("Scale", nil)
@@ -52,7 +69,17 @@ func (mb *MenuBar) Build() *fyne.MainMenu {
 		mb.window.ToggleFullscreen()
 	})
 
-	viewMenu := fyne.NewMenu("View", scaleMenu, fyne.NewMenuItemSeparator(), fullscreenItem)
+	colorMenu := fyne.NewMenuItem("Color Correction", nil)
+	var colorItems []*fyne.MenuItem
+	for _, profile := range ppu.ColorProfiles() {
+		profile := profile
+		colorItems = append(colorItems, fyne.NewMenuItem(profile.String(), func() {
+			mb.window.SetColorProfile(profile)
+		}))
+	}
+	colorMenu.ChildMenu = fyne.NewMenu("", colorItems...)
+
+	viewMenu := fyne.NewMenu("View", scaleMenu, colorMenu, fyne.NewMenuItemSeparator(), fullscreenItem)
 
 	// Help 菜单
 	aboutItem := fyne.NewMenuItem("About", func() {
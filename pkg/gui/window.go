@@ -3,27 +3,42 @@ package gui
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	fyneDialog "fyne.io/fyne/v2/dialog"
+	"gba/pkg/capture"
 	"gba/pkg/gba"
+	"gba/pkg/ppu"
 	nativeDialog "github.com/sqweek/dialog"
 )
 
 const (
 	GameWidth  = 240
 	GameHeight = 160
+
+	// 截图和 GIF 片段快捷键
+	ScreenshotKey = fyne.KeyF12
+	ClipKey       = fyne.KeyF10
 )
 
 type MainWindow struct {
 	app    fyne.App
 	window fyne.Window
 
-	emulator *gba.GBA
-	canvas   *GameCanvas
-	input    *InputHandler
+	emulator    *gba.GBA
+	canvas      *GameCanvas
+	input       *InputHandler
+	screenshots *capture.Screenshotter
+
+	recordDir    string
+	recordFormat string
+
+	// 关闭 stop 通知游戏循环退出，游戏循环退出后关闭 done
+	stop chan struct{}
+	done chan struct{}
 
 	scale int
 }
@@ -33,10 +48,15 @@ func NewMainWindow() *MainWindow {
 	w := a.NewWindow("GBA Emulator")
 
 	mw := &MainWindow{
-		app:      a,
-		window:   w,
-		emulator: gba.New(),
-		scale:    2,
+		app:          a,
+		window:       w,
+		emulator:     gba.New(),
+		screenshots:  capture.NewScreenshotter("screenshots"),
+		recordDir:    "recordings",
+		recordFormat: ".avi",
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+		scale:        2,
 	}
 
 	mw.setupUI()
@@ -46,7 +66,7 @@ func NewMainWindow() *MainWindow {
 
 func (mw *MainWindow) setupUI() {
 	// 创建游戏画面渲染区域
-	mw.canvas = NewGameCanvas(mw.scale)
+	mw.canvas = NewGameCanvas(mw.scale, mw.emulator)
 
 	// 创建输入处理器
 	mw.input = NewInputHandler(mw.emulator)
@@ -56,7 +76,7 @@ func (mw *MainWindow) setupUI() {
 	mw.window.SetMainMenu(menu)
 
 	// 设置内容
-	content := container.NewCenter(mw.canvas.Image())
+	content := container.NewCenter(mw.canvas.Object())
 	mw.window.SetContent(content)
 
 	// 设置窗口大小
@@ -72,6 +92,9 @@ func (mw *MainWindow) createMenu() *fyne.MainMenu {
 
 func (mw *MainWindow) setupKeyboardEvents() {
 	// 在 canvas 中处理键盘事件
+	mw.input.SetHotkey(ScreenshotKey, mw.TakeScreenshot)
+	mw.input.SetHotkey(ClipKey, mw.SaveClip)
+	mw.input.SetupKeyboard(mw.window.Canvas())
 }
 
 func (mw *MainWindow) updateWindowSize() {
@@ -97,6 +120,7 @@ func (mw *MainWindow) LoadROM(filename string) error {
 		return err
 	}
 	fmt.Printf("[GUI] ROM loaded successfully, starting game loop\n")
+	mw.screenshots.Prefix = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
 	return nil
 }
 
@@ -111,31 +135,156 @@ func (mw *MainWindow) LoadBIOS(filename string) error {
 	return nil
 }
 
+func (mw *MainWindow) SetSaveDir(dir string) {
+	mw.emulator.SaveDir = dir
+}
+
+func (mw *MainWindow) SetScreenshotDir(dir string) {
+	mw.screenshots.Dir = dir
+}
+
+// SetScreenshotScale 设置截图放大倍数，1 为原始 240x160
+func (mw *MainWindow) SetScreenshotScale(scale int) {
+	mw.screenshots.Scale = scale
+}
+
+// TakeScreenshot 把最近一帧完整画面保存为 PNG
+func (mw *MainWindow) TakeScreenshot() {
+	img, frame := mw.emulator.PPU.FrameImage()
+	path, err := mw.screenshots.Save(img, frame)
+	if err != nil {
+		fmt.Printf("[GUI] ERROR: Failed to save screenshot: %v\n", err)
+		fyneDialog.ShowError(err, mw.window)
+		return
+	}
+	fmt.Printf("[GUI] Screenshot saved: %s\n", path)
+}
+
+// SetClipSeconds 设置 GIF 片段保留的秒数，0 表示关闭
+func (mw *MainWindow) SetClipSeconds(seconds float64) {
+	mw.emulator.SetClipSeconds(seconds)
+}
+
+// SaveClip 把最近几秒画面保存为 GIF，放在截图目录
+// 画面和颜色方案在调用线程复制，编码在后台进行
+func (mw *MainWindow) SaveClip() {
+	clip, err := mw.emulator.SnapshotClip()
+	if err != nil {
+		mw.showClipError(err)
+		return
+	}
+
+	dir, prefix, scale := mw.screenshots.Dir, mw.screenshots.Prefix, mw.screenshots.Scale
+	go func() {
+		path, err := capture.OutputPath(dir, prefix, ".gif")
+		if err == nil {
+			err = clip.Save(path, scale)
+		}
+		if err != nil {
+			// Fyne 2.4 的对话框可以在 goroutine 中显示
+			mw.showClipError(err)
+			return
+		}
+		fmt.Printf("[GUI] Clip saved: %s\n", path)
+	}()
+}
+
+func (mw *MainWindow) showClipError(err error) {
+	fmt.Printf("[GUI] ERROR: Failed to save clip: %v\n", err)
+	fyneDialog.ShowError(err, mw.window)
+}
+
+func (mw *MainWindow) SetRecordDir(dir string) {
+	mw.recordDir = dir
+}
+
+// SetRecordFormat 设置菜单开始录像时使用的格式：avi 或 y4m
+func (mw *MainWindow) SetRecordFormat(format string) error {
+	switch format {
+	case "avi", "y4m":
+		mw.recordFormat = "." + format
+		return nil
+	default:
+		return fmt.Errorf("unknown recording format %q", format)
+	}
+}
+
+// StartRecording 在录像目录下以时间戳命名开始录像
+func (mw *MainWindow) StartRecording() {
+	path, err := capture.OutputPath(mw.recordDir, mw.screenshots.Prefix, mw.recordFormat)
+	if err == nil {
+		err = mw.StartRecordingTo(path)
+	}
+	if err != nil {
+		fmt.Printf("[GUI] ERROR: Failed to start recording: %v\n", err)
+		fyneDialog.ShowError(err, mw.window)
+	}
+}
+
+func (mw *MainWindow) StartRecordingTo(path string) error {
+	return mw.emulator.StartRecording(path)
+}
+
+func (mw *MainWindow) StopRecording() {
+	if err := mw.emulator.StopRecording(); err != nil {
+		fmt.Printf("[GUI] ERROR: %v\n", err)
+		fyneDialog.ShowError(err, mw.window)
+	}
+}
+
+func (mw *MainWindow) SetColorProfile(profile ppu.ColorProfile) {
+	mw.canvas.SetColorProfile(profile)
+	mw.emulator.PPU.SetColorProfile(profile)
+}
+
 func (mw *MainWindow) Start() {
 	// 启动游戏循环
 	go mw.gameLoop()
 
 	// 运行 Fyne 应用
 	mw.window.ShowAndRun()
+
+	// 窗口关闭后先停止模拟，再结束录像并把未落盘的存档写出
+	close(mw.stop)
+	<-mw.done
+	if err := mw.emulator.StopRecording(); err != nil {
+		fmt.Printf("[GUI] ERROR: %v\n", err)
+	}
+	if err := mw.emulator.FlushSave(); err != nil {
+		fmt.Printf("[GUI] ERROR: Failed to write save file: %v\n", err)
+	}
 }
 
 func (mw *MainWindow) gameLoop() {
 	fmt.Printf("[GUI] Game loop started\n")
+	defer close(mw.done)
+
 	frameCount := 0
+	var lastSeq uint64
 
 	for {
+		select {
+		case <-mw.stop:
+			fmt.Printf("[GUI] Game loop stopped\n")
+			return
+		default:
+		}
+
 		if mw.emulator != nil {
 			mw.emulator.RunFrame()
 
-			// 更新画面
-			frameBuffer := mw.emulator.GetFrameBuffer()
-			mw.canvas.UpdateFrame(frameBuffer)
+			// 只在有新的完整帧时通知画布，画面由 Fyne 绘制线程自己取
+			seq := mw.emulator.FrameSeq()
+			if seq == lastSeq {
+				continue
+			}
+			lastSeq = seq
+			mw.canvas.Refresh()
 
 			frameCount++
 			// 每 60 帧输出一次日志
 			if frameCount%60 == 0 {
-				fmt.Printf("[GUI] Rendered %d frames, FrameBuffer first pixel: 0x%04X\n",
-					frameCount, frameBuffer[0])
+				fmt.Printf("[GUI] Rendered %d frames\n", frameCount)
 			}
 		}
 	}
@@ -3,6 +3,8 @@ package mmu
 import (
 	"encoding/binary"
 	"fmt"
+	"gba/pkg/backup"
+	"gba/pkg/cartridge"
 )
 
 const (
@@ -30,10 +32,27 @@ const (
 	ROMStart  = 0x08000000
 	ROMLength = 0x02000000
 
+	EEPROMStart = 0x0D000000
+	EEPROMEnd   = 0x0E000000
+
 	SRAMStart = 0x0E000000
 	SRAMLenth = 0x00010000
+	SRAMEnd   = 0x10000000
 )
 
+// IODevice 是挂接在 I/O 区域、以 16 位寄存器访问的外设
+type IODevice interface {
+	ReadRegister(addr uint32) uint16
+	WriteRegister(addr uint32, val uint16)
+}
+
+// ByteIODevice 是按字节写入会产生副作用的外设（如声音通道的重新开始位），
+// MMU 直接转发字节写入而不做读-改-写
+type ByteIODevice interface {
+	IODevice
+	WriteRegister8(addr uint32, val uint8)
+}
+
 type MMU struct {
 	BIOS    []byte
 	WRAM256 []byte
@@ -43,65 +62,23 @@ type MMU struct {
 	VRAM    []byte
 	OAM     []byte
 	ROM     []byte
-	SRAM    []byte
 
-	WaitStates [4]int
+	// 0x0E000000 区域的存档芯片（SRAM / Flash），EEPROM 另外映射在 0x0D000000
+	// 卡带没有存档芯片时为 nil
+	Backup backup.Device
+	eeprom *backup.EEPROM
+
+	// 卡带 GPIO 端口（RTC 等）和 SRAM 区域的倾斜传感器，没有时为 nil
+	GPIO *cartridge.GPIO
+	Tilt *cartridge.Tilt
+
+	// 显示寄存器 0x04000000-0x04000057 由 PPU 保存
+	PPU IODevice
+	// 声音寄存器和波形 RAM 0x04000060-0x0400009F 由 APU 保存
+	APU ByteIODevice
+	DMA IODevice
 
-	DISPCNT  uint16
-	DISPSTAT uint16
-	VCOUNT   uint16
-	BG0CNT   uint16
-	BG1CNT   uint16
-	BG2CNT   uint16
-	BG3CNT   uint16
-	BG0HOFS  uint16
-	BG0VOFS  uint16
-	BG1HOFS  uint16
-	BG1VOFS  uint16
-	BG2HOFS  uint16
-	BG2VOFS  uint16
-	BG3HOFS  uint16
-	BG3VOFS  uint16
-	BG2PA    uint16
-	BG2PB    uint16
-	BG2PC    uint16
-	BG2PD    uint16
-	BG2X     uint32
-	BG2Y     uint32
-	BG3PA    uint16
-	BG3PB    uint16
-	BG3PC    uint16
-	BG3PD    uint16
-	BG3X     uint32
-	BG3Y     uint32
-	WIN0H    uint16
-	WIN1H    uint16
-	WIN0V    uint16
-	WIN1V    uint16
-	WININ    uint16
-	WINOUT   uint16
-	MOSAIC   uint16
-	BLDCNT   uint16
-	BLDALPHA uint16
-	BLDY     uint16
-
-	SOUND1CNT_L uint16
-	SOUND1CNT_H uint16
-	SOUND1CNT_X uint16
-	SOUND2CNT_L uint16
-	SOUND2CNT_H uint16
-	SOUND3CNT_L uint16
-	SOUND3CNT_H uint16
-	SOUND3CNT_X uint16
-	SOUND4CNT_L uint16
-	SOUND4CNT_H uint16
-	SOUNDCNT_L  uint16
-	SOUNDCNT_H  uint16
-	SOUNDCNT_X  uint16
-	SOUNDBIAS   uint16
-	WAVE_RAM    [16]byte
-	FIFO_A      [4]byte
-	FIFO_B      [4]byte
+	WaitStates [4]int
 
 	DMA0SAD   uint32
 	DMA0DAD   uint32
@@ -169,7 +146,7 @@ func New() *MMU {
 		VRAM:       make([]byte, VRAMLenth),
 		OAM:        make([]byte, OAMLength),
 		ROM:        make([]byte, 0),
-		SRAM:       make([]byte, SRAMLenth),
+		Backup:     backup.NewSRAM(),
 		WaitStates: [4]int{4, 3, 2, 8},
 	}
 	mmu.Reset()
@@ -195,13 +172,8 @@ func (m *MMU) Reset() {
 	for i := range m.OAM {
 		m.OAM[i] = 0
 	}
-	for i := range m.SRAM {
-		m.SRAM[i] = 0
-	}
+	// 存档芯片由电池供电，复位时保留内容
 
-	m.DISPCNT = 0x0080
-	m.DISPSTAT = 0x0000
-	m.VCOUNT = 0x0000
 	m.IE = 0x0000
 	m.IF = 0x0000
 	m.IME = 0x0000
@@ -218,6 +190,23 @@ func (m *MMU) LoadROM(data []byte) {
 	copy(m.ROM, data)
 }
 
+// SetBackup 替换卡带的存档芯片，换卡时由上层根据存档类型调用
+func (m *MMU) SetBackup(device backup.Device) {
+	m.Backup = device
+	m.eeprom, _ = device.(*backup.EEPROM)
+}
+
+// isEEPROM 判断地址是否落在 EEPROM 区域，32MB 的 ROM 只留出最后 256 字节
+func (m *MMU) isEEPROM(addr uint32) bool {
+	if m.eeprom == nil || addr < EEPROMStart || addr >= EEPROMEnd {
+		return false
+	}
+	if len(m.ROM) > 0x01000000 {
+		return addr >= 0x0DFFFF00
+	}
+	return true
+}
+
 func (m *MMU) mirrorAddress(addr uint32) uint32 {
 	switch {
 	case addr >= 0x00000000 && addr < 0x00004000:
@@ -268,15 +257,28 @@ func (m *MMU) Read8(addr uint32) uint8 {
 	case addr >= OAMStart && addr < OAMStart+OAMLength:
 		return m.OAM[addr-OAMStart]
 	case addr >= ROMStart && addr < ROMStart+uint32(len(m.ROM)):
-		return m.ROM[addr-ROMStart]
-	case addr >= SRAMStart && addr < SRAMStart+SRAMLenth:
-		return m.SRAM[addr-SRAMStart]
+		offset := addr - ROMStart
+		if m.GPIO != nil && m.GPIO.Readable() && cartridge.IsGPIOAddress(offset) {
+			return m.GPIO.Read8(offset)
+		}
+		return m.ROM[offset]
+	case addr >= SRAMStart && addr < SRAMEnd:
+		if m.Tilt != nil && cartridge.IsTiltAddress(addr-SRAMStart) {
+			return m.Tilt.Read8(addr - SRAMStart)
+		}
+		if m.Backup == nil {
+			return 0xFF
+		}
+		return m.Backup.Read8(addr - SRAMStart)
 	default:
 		return 0
 	}
 }
 
 func (m *MMU) Read16(addr uint32) uint16 {
+	if m.isEEPROM(addr) {
+		return m.eeprom.Read16()
+	}
 	return uint16(m.Read8(addr)) | uint16(m.Read8(addr+1))<<8
 }
 
@@ -302,14 +304,26 @@ func (m *MMU) Write8(addr uint32, val uint8) {
 		m.VRAM[addr-VRAMStart] = val
 	case addr >= OAMStart && addr < OAMStart+OAMLength:
 		m.OAM[addr-OAMStart] = val
+	case addr >= SRAMStart && addr < SRAMEnd:
+		if m.Tilt != nil && cartridge.IsTiltAddress(addr-SRAMStart) {
+			m.Tilt.Write8(addr-SRAMStart, val)
+		} else if m.Backup != nil {
+			m.Backup.Write8(addr-SRAMStart, val)
+		}
 	case addr >= ROMStart:
-		// ROM is read-only
-	case addr >= SRAMStart:
-		m.SRAM[addr-SRAMStart] = val
+		// ROM is read-only, only the GPIO registers accept writes
+		offset := addr - ROMStart
+		if m.GPIO != nil && cartridge.IsGPIOAddress(offset) {
+			m.GPIO.Write8(offset, val)
+		}
 	}
 }
 
 func (m *MMU) Write16(addr uint32, val uint16) {
+	if m.isEEPROM(addr) {
+		m.eeprom.Write16(val)
+		return
+	}
 	m.Write8(addr, uint8(val))
 	m.Write8(addr+1, uint8(val>>8))
 }
@@ -323,12 +337,6 @@ func (m *MMU) readIO8(addr uint32) uint8 {
 	offset := addr - IOStart
 
 	switch offset {
-	case 0x00, 0x01:
-		return uint8(m.DISPCNT >> ((offset & 1) * 8))
-	case 0x04, 0x05:
-		return uint8(m.DISPSTAT >> ((offset & 1) * 8))
-	case 0x06, 0x07:
-		return uint8(m.VCOUNT >> ((offset & 1) * 8))
 	case 0x130, 0x131:
 		return uint8(m.KEYINPUT >> ((offset & 1) * 8))
 	case 0x200, 0x201:
@@ -338,6 +346,18 @@ func (m *MMU) readIO8(addr uint32) uint8 {
 	case 0x208, 0x209:
 		return uint8(m.IME >> ((offset & 1) * 8))
 	default:
+		if offset < displayIOEnd && m.PPU != nil {
+			if !displayReadable(offset) {
+				return 0
+			}
+			return uint8(m.PPU.ReadRegister(addr&^1) >> ((offset & 1) * 8))
+		}
+		if offset >= soundIOStart && offset < soundIOEnd && m.APU != nil {
+			return uint8(m.APU.ReadRegister(addr&^1) >> ((offset & 1) * 8))
+		}
+		if offset >= 0xB0 && offset < 0xE0 && m.DMA != nil {
+			return uint8(m.DMA.ReadRegister(addr&^1) >> ((offset & 1) * 8))
+		}
 		if int(offset) < len(m.IO) {
 			return m.IO[offset]
 		}
@@ -349,24 +369,6 @@ func (m *MMU) writeIO8(addr uint32, val uint8) {
 	offset := addr - IOStart
 
 	switch offset {
-	case 0x00:
-		oldVal := m.DISPCNT
-		m.DISPCNT = (m.DISPCNT & 0xFF00) | uint16(val)
-		if oldVal != m.DISPCNT {
-			fmt.Printf("[MMU] DISPCNT write: 0x%04X -> 0x%04X (ForcedBlank: %v)\n",
-				oldVal, m.DISPCNT, m.DISPCNT&0x0080 != 0)
-		}
-	case 0x01:
-		oldVal := m.DISPCNT
-		m.DISPCNT = (m.DISPCNT & 0x00FF) | (uint16(val) << 8)
-		if oldVal != m.DISPCNT {
-			fmt.Printf("[MMU] DISPCNT write: 0x%04X -> 0x%04X (Mode: %d, ForcedBlank: %v)\n",
-				oldVal, m.DISPCNT, m.DISPCNT&0x0007, m.DISPCNT&0x0080 != 0)
-		}
-	case 0x04:
-		m.DISPSTAT = (m.DISPSTAT & 0xFF00) | uint16(val)
-	case 0x05:
-		m.DISPSTAT = (m.DISPSTAT & 0x00FF) | (uint16(val) << 8)
 	case 0x130, 0x131:
 		// KEYINPUT is read-only
 	case 0x200:
@@ -382,12 +384,84 @@ func (m *MMU) writeIO8(addr uint32, val uint8) {
 	case 0x301:
 		m.HALTCNT = val
 	default:
+		if offset < displayIOEnd && m.PPU != nil {
+			m.writeDevice8(m.PPU, addr, val)
+			return
+		}
+		if offset >= soundIOStart && offset < soundIOEnd && m.APU != nil {
+			m.APU.WriteRegister8(addr, val)
+			return
+		}
+		if offset >= 0xB0 && offset < 0xE0 && m.DMA != nil {
+			m.writeDMA8(addr, val)
+			return
+		}
 		if int(offset) < len(m.IO) {
 			m.IO[offset] = val
 		}
 	}
 }
 
+// 显示寄存器和声音寄存器区域的偏移
+const (
+	displayIOEnd = 0x58
+	soundIOStart = 0x60
+	soundIOEnd   = 0xA0
+)
+
+// displayReadable 判断显示寄存器是否可被游戏读取，滚动、仿射参数、窗口坐标、MOSAIC 和 BLDY 只写
+func displayReadable(offset uint32) bool {
+	switch {
+	case offset < 0x10:
+		return true
+	case offset >= 0x48 && offset < 0x4C:
+		return true
+	case offset >= 0x50 && offset < 0x54:
+		return true
+	default:
+		return false
+	}
+}
+
+// mergeByte 将一个字节写入 16 位寄存器的对应半边
+func mergeByte(old uint16, addr uint32, val uint8) uint16 {
+	if addr&1 == 0 {
+		return (old & 0xFF00) | uint16(val)
+	}
+	return (old & 0x00FF) | (uint16(val) << 8)
+}
+
+func (m *MMU) writeDevice8(dev IODevice, addr uint32, val uint8) {
+	reg := addr &^ 1
+	dev.WriteRegister(reg, mergeByte(dev.ReadRegister(reg), addr, val))
+}
+
+func (m *MMU) writeDMA8(addr uint32, val uint8) {
+	reg := addr &^ 1
+	old := m.DMA.ReadRegister(reg)
+	newVal := mergeByte(old, addr, val)
+
+	// DMA3 启动时根据传输长度识别 EEPROM 地址宽度
+	if reg == 0x040000DE && old&0x8000 == 0 && newVal&0x8000 != 0 {
+		m.detectEEPROMSize()
+	}
+
+	m.DMA.WriteRegister(reg, newVal)
+}
+
+func (m *MMU) detectEEPROMSize() {
+	if m.eeprom == nil {
+		return
+	}
+
+	dest := uint32(m.DMA.ReadRegister(0x040000D8)) | uint32(m.DMA.ReadRegister(0x040000DA))<<16
+	if !m.isEEPROM(dest & 0x0FFFFFFF) {
+		return
+	}
+
+	m.eeprom.DetectSize(int(m.DMA.ReadRegister(0x040000DC)))
+}
+
 func (m *MMU) GetVRAM() []byte {
 	return m.VRAM
 }
@@ -417,13 +491,26 @@ func (m *MMU) GetROM() []byte {
 }
 
 func (m *MMU) LoadSave(data []byte) {
-	copy(m.SRAM, data)
+	if m.Backup != nil {
+		m.Backup.Load(data)
+	}
 }
 
 func (m *MMU) GetSaveData() []byte {
-	data := make([]byte, len(m.SRAM))
-	copy(data, m.SRAM)
-	return data
+	if m.Backup == nil {
+		return nil
+	}
+	return m.Backup.Data()
+}
+
+func (m *MMU) IsSaveDirty() bool {
+	return m.Backup != nil && m.Backup.Dirty()
+}
+
+func (m *MMU) ClearSaveDirty() {
+	if m.Backup != nil {
+		m.Backup.ClearDirty()
+	}
 }
 
 func (m *MMU) ReadBIOS(addr uint32) uint32 {
@@ -0,0 +1,103 @@
+package ppu
+
+// 仿射背景四种尺寸，单位像素（正方形）
+var affineBGSizes = [4]int{128, 256, 512, 1024}
+
+// affineRef 返回 BG2/BG3 的内部参考点，供逐行累加
+func (p *PPU) affineRef(bg int) (*int32, *int32) {
+	if bg == 2 {
+		return &p.BG2RefX, &p.BG2RefY
+	}
+	return &p.BG3RefX, &p.BG3RefY
+}
+
+func (p *PPU) affineMatrix(bg int) (pa, pb, pc, pd int32) {
+	if bg == 2 {
+		return int32(p.BG2PA), int32(p.BG2PB), int32(p.BG2PC), int32(p.BG2PD)
+	}
+	return int32(p.BG3PA), int32(p.BG3PB), int32(p.BG3PC), int32(p.BG3PD)
+}
+
+// latchAffine 在 VBlank 时把 BGxX/BGxY 装入内部参考点
+func (p *PPU) latchAffine() {
+	p.BG2RefX = p.BG2X
+	p.BG2RefY = p.BG2Y
+	p.BG3RefX = p.BG3X
+	p.BG3RefY = p.BG3Y
+}
+
+// advanceAffine 在每条可见扫描线结束后按 PB/PD 移动参考点
+func (p *PPU) advanceAffine() {
+	p.BG2RefX += int32(p.BG2PB)
+	p.BG2RefY += int32(p.BG2PD)
+	p.BG3RefX += int32(p.BG3PB)
+	p.BG3RefY += int32(p.BG3PD)
+}
+
+// 28 位有符号定点数（20.8）
+func signExtend28(val uint32) int32 {
+	return int32(val<<4) >> 4
+}
+
+// renderAffineBG 将仿射背景的当前扫描线画到 bgLine[bg]，贴图固定为 8bpp
+func (p *PPU) renderAffineBG(bg int) {
+	cnt := p.bgControl(bg)
+
+	charBase := int((cnt>>bgCharBaseShift)&0x3) * 0x4000
+	screenBase := int((cnt>>bgScreenShift)&0x1F) * 0x800
+	size := affineBGSizes[(cnt>>bgSizeShift)&0x3]
+	tilesPerRow := size / 8
+
+	p.renderAffineLine(bg, size, size, cnt&bgWrap != 0, func(tx, ty int) uint16 {
+		entryAddr := screenBase + (ty/8)*tilesPerRow + tx/8
+		if entryAddr >= bgVRAMLimit {
+			return transparent
+		}
+		tile := int(p.VRAM[entryAddr])
+
+		addr := charBase + tile*64 + (ty%8)*8 + tx%8
+		if addr >= bgVRAMLimit {
+			return transparent
+		}
+
+		colorIdx := p.VRAM[addr]
+		if colorIdx == 0 {
+			return transparent
+		}
+		return p.getPaletteColor(colorIdx)
+	})
+}
+
+// renderAffineLine 用 BG2/BG3 的参考点和 PA/PC 逐像素取样，fetch 返回纹理坐标处的颜色
+// 超出 width x height 时按 wrap 回绕或透明
+func (p *PPU) renderAffineLine(bg, width, height int, wrap bool, fetch func(tx, ty int) uint16) {
+	line := &p.bgLine[bg]
+	cnt := p.bgControl(bg)
+
+	pa, _, pc, _ := p.affineMatrix(bg)
+	refX, refY := p.affineRef(bg)
+	fx, fy := *refX, *refY
+	if cnt&bgMosaic != 0 {
+		fx, fy = p.affineMosaicRef(bg)
+	}
+
+	for x := 0; x < ScreenWidth; x, fx, fy = x+1, fx+pa, fy+pc {
+		tx := int(fx >> 8)
+		ty := int(fy >> 8)
+
+		if wrap {
+			tx = ((tx % width) + width) % width
+			ty = ((ty % height) + height) % height
+		} else if tx < 0 || ty < 0 || tx >= width || ty >= height {
+			line[x] = transparent
+			continue
+		}
+
+		line[x] = fetch(tx, ty)
+	}
+
+	if cnt&bgMosaic != 0 {
+		h, _ := p.bgMosaicSize()
+		applyBGMosaic(line, h)
+	}
+}
@@ -0,0 +1,65 @@
+package ppu
+
+import "testing"
+
+// newAffineBGPPU 准备模式 1 的 128x128 仿射 BG2：地图 (0,0) 为红色图块，(15,0) 为绿色图块
+func newAffineBGPPU(wrap bool) *PPU {
+	p := newTestPPU()
+	p.setColor(0, testBlue)
+	p.setColor(1, testRed)
+	p.setColor(2, testGreen)
+	p.fillTile8(1*64, solid(1))
+	p.fillTile8(2*64, solid(2))
+
+	const screenBase = 0xF800
+	p.VRAM[screenBase] = 1
+	p.VRAM[screenBase+15] = 2
+
+	cnt := uint16(31 << bgScreenShift)
+	if wrap {
+		cnt |= bgWrap
+	}
+	p.WriteRegister(0x04000000, Mode1|BG2Enable)
+	p.WriteRegister(0x0400000C, cnt)
+	return p
+}
+
+// setBG2X 写入 20.8 定点的 BG2X
+func (p *PPU) setBG2X(x int32) {
+	p.WriteRegister(0x04000028, uint16(uint32(x)))
+	p.WriteRegister(0x0400002A, uint16(uint32(x)>>16)&0x0FFF)
+}
+
+func TestAffineBGWrap(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap bool
+		want []span
+	}{
+		// 参考点 x=-4：超出左边的部分透明，x=132 之后超出右边
+		{"no wrap", false, []span{{0, 4, testBlue}, {4, 12, testRed}, {12, 124, testBlue}, {124, 132, testGreen}, {132, 240, testBlue}}},
+		// 回绕时左边取到地图最右列，x=132 处回到第 0 列
+		{"wrap", true, []span{{0, 4, testGreen}, {4, 12, testRed}, {12, 124, testBlue}, {124, 132, testGreen}, {132, 140, testRed}}},
+	}
+
+	for _, tt := range tests {
+		p := newAffineBGPPU(tt.wrap)
+		p.setBG2X(-4 << 8)
+
+		p.runLines(1)
+		expectRow(t, tt.name, p.row(0), tt.want...)
+	}
+}
+
+func TestAffineBGMatrix(t *testing.T) {
+	p := newAffineBGPPU(false)
+
+	// PA = 0.5：每个像素在纹理中前进半个像素，图块放大两倍
+	p.WriteRegister(0x04000020, 0x0080)
+	// PD = 8：每行在纹理中前进 8 行，第二行取到地图第 1 行（空）
+	p.WriteRegister(0x04000026, 0x0800)
+
+	p.runLines(2)
+	expectRow(t, "line 0", p.row(0), span{0, 16, testRed}, span{16, 240, testBlue})
+	expectRow(t, "line 1", p.row(1), span{0, 240, testBlue})
+}
@@ -0,0 +1,138 @@
+package ppu
+
+// BGxCNT 位域
+const (
+	bgPriorityMask  = 0x0003
+	bgCharBaseShift = 2
+	bgMosaic        = 0x0040
+	bg256Color      = 0x0080
+	bgScreenShift   = 8
+	bgWrap          = 0x2000
+	bgSizeShift     = 14
+)
+
+// 行缓冲中用最高位标记透明像素，颜色本身只有 15 位
+const transparent = 0x8000
+
+// BG 贴图数据只能位于 VRAM 前 64KB，后面是 OBJ 区域
+const bgVRAMLimit = 0x10000
+
+// 文本背景四种尺寸，单位像素
+var textBGSizes = [4][2]int{
+	{256, 256},
+	{512, 256},
+	{256, 512},
+	{512, 512},
+}
+
+func (p *PPU) bgControl(bg int) uint16 {
+	switch bg {
+	case 0:
+		return p.BG0CNT
+	case 1:
+		return p.BG1CNT
+	case 2:
+		return p.BG2CNT
+	default:
+		return p.BG3CNT
+	}
+}
+
+func (p *PPU) bgScroll(bg int) (int, int) {
+	switch bg {
+	case 0:
+		return int(p.BG0HOFS & 0x1FF), int(p.BG0VOFS & 0x1FF)
+	case 1:
+		return int(p.BG1HOFS & 0x1FF), int(p.BG1VOFS & 0x1FF)
+	case 2:
+		return int(p.BG2HOFS & 0x1FF), int(p.BG2VOFS & 0x1FF)
+	default:
+		return int(p.BG3HOFS & 0x1FF), int(p.BG3VOFS & 0x1FF)
+	}
+}
+
+func (p *PPU) bgPriority(bg int) int {
+	return int(p.bgControl(bg) & bgPriorityMask)
+}
+
+func (p *PPU) bgEnabled(bg int) bool {
+	return p.DISPCNT&(BG0Enable<<uint(bg)) != 0
+}
+
+func (p *PPU) vram16(offset int) uint16 {
+	return uint16(p.VRAM[offset]) | uint16(p.VRAM[offset+1])<<8
+}
+
+// renderTextBG 将文本背景的当前扫描线画到 bgLine[bg]
+func (p *PPU) renderTextBG(bg int) {
+	line := &p.bgLine[bg]
+	cnt := p.bgControl(bg)
+
+	charBase := int((cnt>>bgCharBaseShift)&0x3) * 0x4000
+	screenBase := int((cnt>>bgScreenShift)&0x1F) * 0x800
+	color256 := cnt&bg256Color != 0
+	size := textBGSizes[(cnt>>bgSizeShift)&0x3]
+	width, height := size[0], size[1]
+
+	srcLine := p.CurrentLine
+	if cnt&bgMosaic != 0 {
+		srcLine = p.bgMosaicY
+	}
+
+	hofs, vofs := p.bgScroll(bg)
+	y := (srcLine + vofs) % height
+
+	for x := 0; x < ScreenWidth; x++ {
+		bx := (x + hofs) % width
+
+		// 每个屏幕块 32x32 个图块，宽 512 时块按行排列
+		block := bx/256 + (y/256)*(width/256)
+		entryAddr := screenBase + block*0x800 + ((y%256)/8)*64 + ((bx%256)/8)*2
+		if entryAddr+1 >= len(p.VRAM) {
+			line[x] = transparent
+			continue
+		}
+		entry := p.vram16(entryAddr)
+
+		tile := int(entry & 0x3FF)
+		px := bx % 8
+		py := y % 8
+		if entry&0x0400 != 0 {
+			px = 7 - px
+		}
+		if entry&0x0800 != 0 {
+			py = 7 - py
+		}
+
+		var colorIdx uint8
+		if color256 {
+			addr := charBase + tile*64 + py*8 + px
+			if addr >= bgVRAMLimit {
+				line[x] = transparent
+				continue
+			}
+			colorIdx = p.VRAM[addr]
+		} else {
+			addr := charBase + tile*32 + py*4 + px/2
+			if addr >= bgVRAMLimit {
+				line[x] = transparent
+				continue
+			}
+			colorIdx = (p.VRAM[addr] >> uint((px&1)*4)) & 0xF
+			if colorIdx != 0 {
+				colorIdx += uint8(entry>>12) * 16
+			}
+		}
+
+		if colorIdx == 0 {
+			line[x] = transparent
+			continue
+		}
+		line[x] = p.getPaletteColor(colorIdx)
+	}
+
+	if cnt&bgMosaic != 0 {
+		h, _ := p.bgMosaicSize()
+		applyBGMosaic(line, h)
+	}
+}
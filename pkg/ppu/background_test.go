@@ -0,0 +1,89 @@
+package ppu
+
+import "testing"
+
+const (
+	testRed   = 0x001F
+	testGreen = 0x03E0
+	testBlue  = 0x7C00
+)
+
+// newTextBGPPU 准备模式 0 的 BG0：图块 1 为红色，图块 2 为绿色，屏幕块从 0xF000 开始
+func newTextBGPPU(size uint16) *PPU {
+	p := newTestPPU()
+	p.setColor(0, testBlue)
+	p.setColor(1, testRed)
+	p.setColor(2, testGreen)
+	p.fillTile4(1*32, solid(1))
+	p.fillTile4(2*32, solid(2))
+
+	p.WriteRegister(0x04000000, Mode0|BG0Enable)
+	p.WriteRegister(0x04000008, 30<<bgScreenShift|size<<bgSizeShift)
+	return p
+}
+
+// setTextEntry 设置第 block 个屏幕块中 (tx, ty) 处的图块
+func (p *PPU) setTextEntry(block, tx, ty int, entry uint16) {
+	p.write16(0xF000+block*0x800+ty*64+tx*2, entry)
+}
+
+func TestTextBGScrollWrap(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       uint16
+		hofs, vofs uint16
+		want       []span
+	}{
+		{"no scroll", 0, 0, 0, []span{{0, 8, testRed}, {8, 240, testBlue}}},
+		{"scroll right", 0, 4, 0, []span{{0, 4, testRed}, {4, 240, testBlue}}},
+		// 256 宽的背景在 x=256 处回绕到 0
+		{"wrap left edge", 0, 252, 0, []span{{0, 4, testGreen}, {4, 12, testRed}, {12, 240, testBlue}}},
+		{"wrap top edge", 0, 0, 248, []span{{0, 8, testGreen}, {8, 240, testBlue}}},
+		// 滚动寄存器只有 9 位
+		{"scroll register masked", 0, 0x200 | 252, 0, []span{{0, 4, testGreen}, {4, 12, testRed}}},
+		// 512 宽时右半边来自第二个屏幕块，回绕发生在 x=512
+		{"512 wide", 1, 508, 0, []span{{0, 4, testGreen}, {4, 12, testRed}, {12, 240, testBlue}}},
+		{"512 wide second block", 1, 256, 0, []span{{0, 8, testGreen}, {8, 240, testBlue}}},
+	}
+
+	for _, tt := range tests {
+		p := newTextBGPPU(tt.size)
+		p.setTextEntry(0, 0, 0, 1)
+		p.setTextEntry(0, 31, 0, 2)
+		p.setTextEntry(0, 0, 31, 2)
+		if tt.size == 1 {
+			p.setTextEntry(0, 31, 0, 0)
+			p.setTextEntry(0, 0, 31, 0)
+			p.setTextEntry(1, 0, 0, 2)
+			p.setTextEntry(1, 31, 0, 2)
+		}
+		p.WriteRegister(0x04000010, tt.hofs)
+		p.WriteRegister(0x04000012, tt.vofs)
+
+		p.runLines(1)
+		expectRow(t, tt.name, p.row(0), tt.want...)
+	}
+}
+
+func TestTextBGFlipAndPalette(t *testing.T) {
+	p := newTextBGPPU(0)
+	// 图块 3 左半边索引 1，右半边索引 2
+	p.fillTile4(3*32, func(x, y int) uint8 {
+		if x < 4 {
+			return 1
+		}
+		return 2
+	})
+	p.setColor(16+1, testGreen)
+
+	p.setTextEntry(0, 0, 0, 3)
+	p.setTextEntry(0, 1, 0, 3|0x0400)
+	// 调色板 1 中的索引 1
+	p.setTextEntry(0, 2, 0, 1|1<<12)
+
+	p.runLines(1)
+	expectRow(t, "flip", p.row(0),
+		span{0, 4, testRed}, span{4, 8, testGreen},
+		span{8, 12, testGreen}, span{12, 16, testRed},
+		span{16, 24, testGreen})
+}
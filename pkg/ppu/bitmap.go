@@ -0,0 +1,43 @@
+package ppu
+
+const (
+	bitmapPageSize = 0xA000
+	mode5Width     = 160
+	mode5Height    = 128
+
+	// 位图模式下 OBJ 图块只能使用 VRAM 0x14000 之后的部分，即图块号 512 起
+	bitmapObjTileBase = 512
+)
+
+func isBitmapMode(mode uint16) bool {
+	return mode >= Mode3 && mode <= Mode5
+}
+
+// renderBitmapBG 将模式 3/4/5 的位图作为 BG2 经仿射引擎画到 bgLine[2]，
+// 支持缩放旋转，位图范围外透明（显示背景色），不回绕
+func (p *PPU) renderBitmapBG(mode uint16) {
+	// 模式 3 只有一帧，模式 4/5 由 DISPCNT bit4 选择页
+	page := 0
+	if mode != Mode3 && p.DISPCNT&FrameSelect != 0 {
+		page = bitmapPageSize
+	}
+
+	switch mode {
+	case Mode3:
+		p.renderAffineLine(2, ScreenWidth, ScreenHeight, false, func(x, y int) uint16 {
+			return p.vram16((y*ScreenWidth+x)*2) & 0x7FFF
+		})
+	case Mode4:
+		p.renderAffineLine(2, ScreenWidth, ScreenHeight, false, func(x, y int) uint16 {
+			idx := p.VRAM[page+y*ScreenWidth+x]
+			if idx == 0 {
+				return transparent
+			}
+			return p.getPaletteColor(idx)
+		})
+	default:
+		p.renderAffineLine(2, mode5Width, mode5Height, false, func(x, y int) uint16 {
+			return p.vram16(page+(y*mode5Width+x)*2) & 0x7FFF
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package ppu
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sync"
+)
+
+// ColorProfile 选择 15 位 BGR555 到 RGBA 的输出转换
+type ColorProfile int
+
+const (
+	// ColorNone 精确展开 5 位到 8 位，不做校正
+	ColorNone ColorProfile = iota
+	// ColorGBA 模拟原版 GBA 无背光屏幕：暗部压低、色彩偏淡
+	ColorGBA
+	// ColorGBALight 较轻的校正，只做通道混合和轻微降亮度
+	ColorGBALight
+)
+
+var colorProfileNames = [...]string{
+	ColorNone:     "none",
+	ColorGBA:      "gba",
+	ColorGBALight: "gba-light",
+}
+
+func (c ColorProfile) String() string {
+	if c >= 0 && int(c) < len(colorProfileNames) {
+		return colorProfileNames[c]
+	}
+	return fmt.Sprintf("ColorProfile(%d)", int(c))
+}
+
+// ColorProfiles 返回所有可选的校正方案，供前端列出
+func ColorProfiles() []ColorProfile {
+	return []ColorProfile{ColorNone, ColorGBA, ColorGBALight}
+}
+
+func ParseColorProfile(name string) (ColorProfile, error) {
+	for i, n := range colorProfileNames {
+		if n == name {
+			return ColorProfile(i), nil
+		}
+	}
+	return ColorNone, fmt.Errorf("unknown color profile %q", name)
+}
+
+// colorMatrix 描述一种 LCD 校正：先按 lcdGamma 线性化并乘以 linearLum，
+// 再用 mix 混合三个通道（行为输出 R/G/B，列为输入 R/G/B），最后按 outGamma 编码并乘以 outLum
+type colorMatrix struct {
+	lcdGamma  float64
+	outGamma  float64
+	linearLum float64
+	outLum    float64
+	mix       [3][3]float64
+}
+
+var colorMatrices = map[ColorProfile]colorMatrix{
+	// 参考 higan 的 GBA 色彩模拟
+	ColorGBA: {
+		lcdGamma:  4.0,
+		outGamma:  2.2,
+		linearLum: 1,
+		outLum:    255.0 / 280.0,
+		mix: [3][3]float64{
+			{255.0 / 255, 50.0 / 255, 0},
+			{10.0 / 255, 230.0 / 255, 30.0 / 255},
+			{50.0 / 255, 10.0 / 255, 220.0 / 255},
+		},
+	},
+	// 参考 libretro 的 gba-color 着色器
+	ColorGBALight: {
+		lcdGamma:  2.2,
+		outGamma:  2.2,
+		linearLum: 0.94,
+		outLum:    1,
+		mix: [3][3]float64{
+			{0.82, 0.24, -0.06},
+			{0.125, 0.665, 0.21},
+			{0.195, 0.075, 0.73},
+		},
+	},
+}
+
+// SetColorProfile 设置 ToImage 使用的颜色校正方案，可在任意线程调用
+func (p *PPU) SetColorProfile(profile ColorProfile) {
+	p.colorProfile.Store(int32(profile))
+}
+
+func (p *PPU) ColorProfile() ColorProfile {
+	return ColorProfile(p.colorProfile.Load())
+}
+
+var (
+	colorTables   = map[ColorProfile]*[0x8000]color.RGBA{}
+	colorTablesMu sync.Mutex
+)
+
+// Expand5 将 5 位颜色分量精确展开到 8 位，31 对应 255
+func Expand5(v uint16) uint8 {
+	v &= 0x1F
+	return uint8(v<<3 | v>>2)
+}
+
+// ColorTable 返回指定方案下全部 32768 种颜色的查找表，首次使用时生成
+func ColorTable(profile ColorProfile) *[0x8000]color.RGBA {
+	colorTablesMu.Lock()
+	defer colorTablesMu.Unlock()
+
+	if t, ok := colorTables[profile]; ok {
+		return t
+	}
+
+	t := new([0x8000]color.RGBA)
+	m, correct := colorMatrices[profile]
+	for c := range t {
+		if correct {
+			t[c] = m.convert(uint16(c))
+		} else {
+			t[c] = color.RGBA{Expand5(uint16(c)), Expand5(uint16(c) >> 5), Expand5(uint16(c) >> 10), 255}
+		}
+	}
+	colorTables[profile] = t
+	return t
+}
+
+func (m colorMatrix) convert(c uint16) color.RGBA {
+	var in [3]float64
+	for i := range in {
+		in[i] = math.Pow(float64((c>>(uint(i)*5))&0x1F)/31, m.lcdGamma) * m.linearLum
+	}
+
+	var out [3]uint8
+	for i := range out {
+		v := m.mix[i][0]*in[0] + m.mix[i][1]*in[1] + m.mix[i][2]*in[2]
+		v = math.Pow(math.Max(0, v), 1/m.outGamma) * m.outLum
+		out[i] = uint8(math.Round(math.Min(1, v) * 255))
+	}
+	return color.RGBA{out[0], out[1], out[2], 255}
+}
+
+// applyGreenSwap 实现未公开的 GREENSWAP 寄存器：相邻两个像素交换绿色分量
+func applyGreenSwap(row []uint16) {
+	const green = 0x03E0
+	for x := 0; x+1 < len(row); x += 2 {
+		a, b := row[x], row[x+1]
+		row[x] = a&^green | b&green
+		row[x+1] = b&^green | a&green
+	}
+}
@@ -0,0 +1,149 @@
+package ppu
+
+// 图层编号，与 BLDCNT 的目标位对应；layerIDNone 表示下面没有第二层
+const (
+	layerIDOBJ      = 4
+	layerIDBackdrop = 5
+	layerIDNone     = -1
+)
+
+// BLDCNT 特效模式
+const (
+	blendNone     = 0
+	blendAlpha    = 1
+	blendBrighten = 2
+	blendDarken   = 3
+)
+
+type layerPixel struct {
+	color uint16
+	id    int
+	semi  bool
+}
+
+// composeLine 按 BGxCNT 优先级合成背景和精灵，并对最上面两层应用颜色特效
+// 优先级相同时编号小的背景在上，精灵在同优先级背景之上
+func (p *PPU) composeLine(bgs ...int) {
+	var order []int
+	for priority := 0; priority < 4; priority++ {
+		for _, bg := range bgs {
+			if p.bgEnabled(bg) && p.bgPriority(bg) == priority {
+				order = append(order, bg)
+			}
+		}
+	}
+
+	backdrop := layerPixel{color: p.getPaletteColor(0), id: layerIDBackdrop}
+	row := p.FrameBuffer[p.CurrentLine*ScreenWidth : (p.CurrentLine+1)*ScreenWidth]
+
+	for x := range row {
+		mask := p.windowMask[x]
+		objVisible := mask&layerOBJ != 0 && p.objLine[x]&transparent == 0
+
+		layers := [2]layerPixel{{id: layerIDNone}, {id: layerIDNone}}
+		n := 0
+		push := func(l layerPixel) {
+			if n < 2 {
+				layers[n] = l
+				n++
+			}
+		}
+
+		for _, bg := range order {
+			if n == 2 {
+				break
+			}
+			if mask&(1<<uint(bg)) == 0 {
+				continue
+			}
+			c := p.bgLine[bg][x]
+			if c&transparent != 0 {
+				continue
+			}
+			if objVisible && p.objPrio[x] <= uint8(p.bgPriority(bg)) {
+				push(layerPixel{color: p.objLine[x], id: layerIDOBJ, semi: p.objSemi[x]})
+				objVisible = false
+			}
+			push(layerPixel{color: c, id: bg})
+		}
+		if objVisible {
+			push(layerPixel{color: p.objLine[x], id: layerIDOBJ, semi: p.objSemi[x]})
+		}
+		// 背景色只有一层，它下面不再有可混合的目标
+		push(backdrop)
+
+		if mask&layerEffects == 0 {
+			row[x] = layers[0].color
+			continue
+		}
+		row[x] = p.applyEffect(layers[0], layers[1])
+	}
+}
+
+func (p *PPU) applyEffect(top, bottom layerPixel) uint16 {
+	first := p.BLDCNT & 0x3F
+	second := (p.BLDCNT >> 8) & 0x3F
+	topIsFirst := first&(1<<uint(top.id)) != 0
+	bottomIsSecond := bottom.id != layerIDNone && second&(1<<uint(bottom.id)) != 0
+
+	// 半透明精灵总是作为第一目标并强制使用 alpha 混合
+	if top.semi && bottomIsSecond {
+		return p.alphaBlend(top.color, bottom.color)
+	}
+
+	if !topIsFirst {
+		return top.color
+	}
+
+	switch (p.BLDCNT >> 6) & 0x3 {
+	case blendAlpha:
+		if bottomIsSecond {
+			return p.alphaBlend(top.color, bottom.color)
+		}
+	case blendBrighten:
+		return p.brightness(top.color, true)
+	case blendDarken:
+		return p.brightness(top.color, false)
+	}
+	return top.color
+}
+
+func blendCoefficient(v uint16) int {
+	if v > 16 {
+		return 16
+	}
+	return int(v)
+}
+
+func (p *PPU) alphaBlend(a, b uint16) uint16 {
+	eva := blendCoefficient(p.BLDALPHA & 0x1F)
+	evb := blendCoefficient((p.BLDALPHA >> 8) & 0x1F)
+
+	var out uint16
+	for shift := uint(0); shift < 15; shift += 5 {
+		ca := int(a>>shift) & 0x1F
+		cb := int(b>>shift) & 0x1F
+		c := (ca*eva + cb*evb) >> 4
+		if c > 31 {
+			c = 31
+		}
+		out |= uint16(c) << shift
+	}
+	return out
+}
+
+func (p *PPU) brightness(col uint16, increase bool) uint16 {
+	evy := blendCoefficient(p.BLDY & 0x1F)
+
+	var out uint16
+	for shift := uint(0); shift < 15; shift += 5 {
+		c := int(col>>shift) & 0x1F
+		if increase {
+			c += ((31 - c) * evy) >> 4
+		} else {
+			c -= (c * evy) >> 4
+		}
+		out |= uint16(c) << shift
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package ppu
+
+import "testing"
+
+func TestBlendModes(t *testing.T) {
+	const (
+		bg0      = 0x01
+		bg1      = 0x02
+		bg2      = 0x04
+		backdrop = 0x20
+	)
+
+	tests := []struct {
+		name     string
+		layers   uint16
+		backdrop uint16
+		bldcnt   uint16
+		bldalpha uint16
+		bldy     uint16
+		want     uint16
+	}{
+		{"none", BG0Enable | BG1Enable, testBlue, bg0 | bg1<<8, 8 | 8<<8, 0, testRed},
+
+		// alpha：红 31 * 8/16 + 绿 31 * 8/16
+		{"alpha", BG0Enable | BG1Enable, testBlue, bg0 | blendAlpha<<6 | bg1<<8, 8 | 8<<8, 0, 15 | 15<<5},
+		{"alpha saturates", BG0Enable | BG1Enable, testBlue, bg0 | blendAlpha<<6 | bg1<<8, 16 | 16<<8, 0, testRed | testGreen},
+		{"alpha coefficient capped at 16", BG0Enable | BG1Enable, testBlue, bg0 | blendAlpha<<6 | bg1<<8, 31 | 0<<8, 0, testRed},
+		{"alpha second target missing", BG0Enable | BG1Enable, testBlue, bg0 | blendAlpha<<6 | bg2<<8, 8 | 8<<8, 0, testRed},
+		{"alpha top not first target", BG0Enable | BG1Enable, testBlue, bg1 | blendAlpha<<6 | bg1<<8, 8 | 8<<8, 0, testRed},
+		// 只有一层背景时下面是背景色
+		{"alpha over backdrop", BG0Enable, testBlue, bg0 | blendAlpha<<6 | backdrop<<8, 8 | 8<<8, 0, 15 | 15<<10},
+		// 没有任何图层时背景色下面没有第二层，不能和自己混合
+		{"alpha backdrop alone", 0, 16 << 10, backdrop | blendAlpha<<6 | backdrop<<8, 16 | 16<<8, 0, 16 << 10},
+
+		{"brighten", BG0Enable | BG1Enable, testBlue, bg0 | blendBrighten<<6, 0, 8, 31 | 15<<5 | 15<<10},
+		{"brighten full", BG0Enable | BG1Enable, testBlue, bg0 | blendBrighten<<6, 0, 31, 0x7FFF},
+		{"brighten backdrop", 0, 16 << 10, backdrop | blendBrighten<<6, 0, 8, 15 | 15<<5 | 23<<10},
+
+		{"darken", BG0Enable | BG1Enable, testBlue, bg0 | blendDarken<<6, 0, 8, 16},
+		{"darken full", BG0Enable | BG1Enable, testBlue, bg0 | blendDarken<<6, 0, 16, 0},
+		{"darken top not first target", BG0Enable | BG1Enable, testBlue, bg1 | blendDarken<<6, 0, 16, testRed},
+	}
+
+	for _, tt := range tests {
+		p := newTwoLayerPPU()
+		p.setColor(0, tt.backdrop)
+		p.WriteRegister(0x04000000, Mode0|tt.layers)
+		p.WriteRegister(0x04000050, tt.bldcnt)
+		p.WriteRegister(0x04000052, tt.bldalpha)
+		p.WriteRegister(0x04000054, tt.bldy)
+
+		p.runLines(1)
+		expectRow(t, tt.name, p.row(0), span{0, 240, tt.want})
+	}
+}
+
+func TestBlendWindowEffectsBit(t *testing.T) {
+	p := newTwoLayerPPU()
+	p.WriteRegister(0x04000000, Mode0|BG0Enable|BG1Enable|Win0Enable)
+	p.WriteRegister(0x04000050, 0x01|blendDarken<<6)
+	p.WriteRegister(0x04000054, 16)
+
+	// WIN0 = [0, 120) 不允许颜色特效，窗口外允许
+	p.WriteRegister(0x04000040, 0<<8|120)
+	p.WriteRegister(0x04000044, 0<<8|160)
+	p.WriteRegister(0x04000048, 0x03)
+	p.WriteRegister(0x0400004A, 0x03|layerEffects)
+
+	p.runLines(1)
+	expectRow(t, "effects bit", p.row(0), span{0, 120, testRed}, span{120, 240, 0})
+}
+
+func TestBlendSemiTransparentObj(t *testing.T) {
+	p := newTwoLayerPPU()
+	p.setColor(256+1, testRed)
+	p.fillTile4(objCharBase, solid(1))
+	for i := 0; i < objCount; i++ {
+		p.setObj(i, objDisable, 0, 0)
+	}
+	// 半透明精灵在 BG1 之上，即使 BLDCNT 没有选择特效也强制 alpha 混合
+	p.setObj(0, objModeSemi<<objModeShift, 0, 0)
+
+	p.WriteRegister(0x04000000, Mode0|BG1Enable|ObjEnable|Obj1DMap)
+	p.WriteRegister(0x04000050, 0x02<<8)
+	p.WriteRegister(0x04000052, 8|8<<8)
+
+	p.runLines(1)
+	expectRow(t, "semi obj", p.row(0), span{0, 8, 15 | 15<<5}, span{8, 240, testGreen})
+}
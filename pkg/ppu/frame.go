@@ -0,0 +1,80 @@
+package ppu
+
+import "sync"
+
+// frameHandoff 在模拟线程和显示线程之间交接完整的帧，共三块缓冲：
+// FrameBuffer 是正在绘制的后台缓冲，只能由调用 Step 的线程访问；
+// ready 保存最近完成的一帧，每次进入 VBlank 时从 FrameBuffer 复制过来；
+// front 在 AcquireFrame 和 ReleaseFrame 之间归显示线程所有，PPU 不会写它
+type frameHandoff struct {
+	mu       sync.Mutex
+	ready    []uint16
+	front    []uint16
+	seq      uint64
+	frontSeq uint64
+	acquired bool
+}
+
+func (p *PPU) publishFrame() {
+	p.frames.mu.Lock()
+	copy(p.frames.ready, p.FrameBuffer)
+	p.frames.seq++
+	p.frames.mu.Unlock()
+}
+
+// AcquireFrame 取得最近一帧完整画面的所有权，返回画面和它的帧序号，可在任意线程调用
+// 返回的切片在 ReleaseFrame 之前保持不变，之后不能再使用；两次调用之间必须 ReleaseFrame
+func (p *PPU) AcquireFrame() ([]uint16, uint64) {
+	p.frames.mu.Lock()
+	defer p.frames.mu.Unlock()
+
+	f := &p.frames
+	if !f.acquired && f.seq > f.frontSeq {
+		f.front, f.ready = f.ready, f.front
+		f.frontSeq = f.seq
+	}
+	f.acquired = true
+	return f.front, f.frontSeq
+}
+
+// ReleaseFrame 归还 AcquireFrame 取得的画面
+func (p *PPU) ReleaseFrame() {
+	p.frames.mu.Lock()
+	p.frames.acquired = false
+	p.frames.mu.Unlock()
+}
+
+// latest 返回 ready 和 front 中较新的一帧，调用方需持有锁
+func (f *frameHandoff) latest() []uint16 {
+	// 交换后 front 是最新的，直到下一次 publishFrame 写入 ready
+	if f.seq != 0 && f.frontSeq == f.seq {
+		return f.front
+	}
+	return f.ready
+}
+
+// CopyFrame 把最近一帧完整画面复制到 dst，返回该帧的序号（复位后每帧加 1），可在任意线程调用
+// 序号未变化说明没有新帧，调用方可以跳过刷新
+func (p *PPU) CopyFrame(dst []uint16) uint64 {
+	p.frames.mu.Lock()
+	defer p.frames.mu.Unlock()
+	copy(dst, p.frames.latest())
+	return p.frames.seq
+}
+
+func (p *PPU) FrameSeq() uint64 {
+	p.frames.mu.Lock()
+	defer p.frames.mu.Unlock()
+	return p.frames.seq
+}
+
+// resetFrames 清空 ready，front 可能正被显示线程持有，只作废它的序号
+func (p *PPU) resetFrames() {
+	p.frames.mu.Lock()
+	for i := range p.frames.ready {
+		p.frames.ready[i] = 0
+	}
+	p.frames.seq = 0
+	p.frames.frontSeq = 0
+	p.frames.mu.Unlock()
+}
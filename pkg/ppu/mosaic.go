@@ -0,0 +1,77 @@
+package ppu
+
+func (p *PPU) bgMosaicSize() (int, int) {
+	return int(p.MOSAIC&0xF) + 1, int((p.MOSAIC>>4)&0xF) + 1
+}
+
+func (p *PPU) objMosaicSize() (int, int) {
+	return int((p.MOSAIC>>8)&0xF) + 1, int((p.MOSAIC>>12)&0xF) + 1
+}
+
+// resetMosaic 在 VBlank 时清零垂直马赛克计数器，与仿射参考点同时装入
+func (p *PPU) resetMosaic() {
+	p.bgMosaicY, p.bgMosaicCount = 0, 0
+	p.objMosaicY, p.objMosaicCount = 0, 0
+	p.latchMosaicRef()
+}
+
+func (p *PPU) latchMosaicRef() {
+	p.bg2MosaicX, p.bg2MosaicY = p.BG2RefX, p.BG2RefY
+	p.bg3MosaicX, p.bg3MosaicY = p.BG3RefX, p.BG3RefY
+}
+
+// stepMosaic 在每条可见扫描线结束后推进计数器，计满一块时下一行重新取样
+// 仿射背景在取样行同时记下参考点，块内其余行沿用该参考点
+func (p *PPU) stepMosaic() {
+	_, bgV := p.bgMosaicSize()
+	_, objV := p.objMosaicSize()
+
+	p.bgMosaicCount++
+	if p.bgMosaicCount >= bgV {
+		p.bgMosaicCount = 0
+		p.bgMosaicY = p.CurrentLine + 1
+		p.latchMosaicRef()
+	}
+
+	p.objMosaicCount++
+	if p.objMosaicCount >= objV {
+		p.objMosaicCount = 0
+		p.objMosaicY = p.CurrentLine + 1
+	}
+}
+
+func (p *PPU) affineMosaicRef(bg int) (int32, int32) {
+	if bg == 2 {
+		return p.bg2MosaicX, p.bg2MosaicY
+	}
+	return p.bg3MosaicX, p.bg3MosaicY
+}
+
+// applyBGMosaic 水平方向每 h 个像素重复块内第一个像素
+func applyBGMosaic(line *[ScreenWidth]uint16, h int) {
+	if h <= 1 {
+		return
+	}
+	for x := 0; x < ScreenWidth; x++ {
+		line[x] = line[x-x%h]
+	}
+}
+
+// objMosaicX 返回精灵马赛克下屏幕坐标 x 实际取样的位置，不越过精灵左边界
+func (p *PPU) objMosaicX(x, x0 int) int {
+	h, _ := p.objMosaicSize()
+	mx := x - x%h
+	if mx < x0 {
+		mx = x0
+	}
+	return mx
+}
+
+// objMosaicDY 返回精灵马赛克下当前行实际取样的精灵内行号
+func (p *PPU) objMosaicDY(dy int) int {
+	mdy := dy - (p.CurrentLine - p.objMosaicY)
+	if mdy < 0 {
+		mdy = 0
+	}
+	return mdy
+}
@@ -0,0 +1,75 @@
+package ppu
+
+import "testing"
+
+// newMosaicPPU 准备模式 0 的 BG0，整屏铺满 pixel 描述的 4bpp 图块 0，颜色索引 i 的颜色为 i
+func newMosaicPPU(pixel func(x, y int) uint8) *PPU {
+	p := newTestPPU()
+	for i := 0; i < 16; i++ {
+		p.setColor(i, uint16(i))
+	}
+	p.fillTile4(0, pixel)
+	p.WriteRegister(0x04000000, Mode0|BG0Enable)
+	p.WriteRegister(0x04000008, 31<<bgScreenShift|bgMosaic)
+	return p
+}
+
+func TestBGMosaicHorizontal(t *testing.T) {
+	p := newMosaicPPU(func(x, y int) uint8 { return uint8(x + 1) })
+	p.WriteRegister(0x0400004C, 3)
+
+	p.runLines(1)
+	expectRow(t, "h=4", p.row(0),
+		span{0, 4, 1}, span{4, 8, 5}, span{8, 12, 1}, span{12, 16, 5})
+}
+
+func TestBGMosaicVertical(t *testing.T) {
+	p := newMosaicPPU(func(x, y int) uint8 { return uint8(y + 1) })
+	p.WriteRegister(0x0400004C, 3<<4)
+
+	p.runLines(10)
+	for y, want := range []uint16{1, 1, 1, 1, 5, 5, 5, 5, 1, 1} {
+		expectRow(t, "v=4", p.row(y), span{0, 240, want})
+	}
+}
+
+func TestBGMosaicDisabledPerLayer(t *testing.T) {
+	p := newMosaicPPU(func(x, y int) uint8 { return uint8(x + 1) })
+	p.WriteRegister(0x04000008, 31<<bgScreenShift)
+	p.WriteRegister(0x0400004C, 3)
+
+	p.runLines(1)
+	expectRow(t, "no mosaic bit", p.row(0), span{0, 1, 1}, span{1, 2, 2}, span{7, 8, 8})
+}
+
+// 块中间写入 BG2Y 后，当前行立即从新的参考点取样
+func TestAffineMosaicOriginWrite(t *testing.T) {
+	p := newAffineBGPPU(false)
+	p.VRAM[0xF800+4*16] = 2
+	p.WriteRegister(0x0400000C, 31<<bgScreenShift|bgMosaic)
+	p.WriteRegister(0x0400004C, 3<<4)
+
+	p.runLines(2)
+	p.WriteRegister(0x0400002C, 32<<8)
+	p.runLines(1)
+
+	expectRow(t, "before write", p.row(1), span{0, 8, testRed})
+	expectRow(t, "after write", p.row(2), span{0, 8, testGreen})
+}
+
+func TestObjMosaic(t *testing.T) {
+	p := newObjPPU(Obj1DMap)
+	for i := 0; i < 16; i++ {
+		p.setColor(256+i, uint16(i))
+	}
+	p.fillTile4(objCharBase, func(x, y int) uint8 { return uint8(x + 1) })
+	p.fillTile4(objCharBase+32, func(x, y int) uint8 { return uint8(x + 8) })
+	p.WriteRegister(0x0400004C, 3<<8)
+
+	// 精灵从 x=2 开始，马赛克块按屏幕坐标对齐，但不会取到精灵左边界之外
+	p.setObj(0, objMosaic, 1<<objSizeShift|2, 0)
+
+	p.runLines(1)
+	expectRow(t, "obj h=4", p.row(0),
+		span{2, 4, 1}, span{4, 8, 3}, span{8, 12, 7}, span{12, 16, 10}, span{16, 18, 14})
+}
@@ -0,0 +1,294 @@
+package ppu
+
+// OAM 属性位域
+const (
+	objAffine     = 0x0100
+	objDisable    = 0x0200
+	objModeShift  = 10
+	objMosaic     = 0x1000
+	obj256Color   = 0x2000
+	objShapeShift = 14
+
+	objHFlip       = 0x1000
+	objVFlip       = 0x2000
+	objAffineShift = 9
+	objSizeShift   = 14
+)
+
+// OBJ 模式
+const (
+	objModeNormal = 0
+	objModeSemi   = 1
+	objModeWindow = 2
+)
+
+const (
+	objCount     = 128
+	objCharBase  = 0x10000
+	objPalette   = 0x200
+	objCycles    = 1210
+	objCyclesHBF = 954
+)
+
+// objSizes[shape][size] = {宽, 高}
+var objSizes = [4][4][2]int{
+	{{8, 8}, {16, 16}, {32, 32}, {64, 64}},
+	{{16, 8}, {32, 8}, {32, 16}, {64, 32}},
+	{{8, 16}, {8, 32}, {16, 32}, {32, 64}},
+	{{8, 8}, {8, 8}, {8, 8}, {8, 8}},
+}
+
+type objAttrs struct {
+	attr0 uint16
+	attr1 uint16
+	attr2 uint16
+}
+
+func (p *PPU) readObj(i int) objAttrs {
+	base := i * 8
+	return objAttrs{
+		attr0: uint16(p.OAM[base]) | uint16(p.OAM[base+1])<<8,
+		attr1: uint16(p.OAM[base+2]) | uint16(p.OAM[base+3])<<8,
+		attr2: uint16(p.OAM[base+4]) | uint16(p.OAM[base+5])<<8,
+	}
+}
+
+func (o objAttrs) size() (int, int) {
+	s := objSizes[o.attr0>>objShapeShift][o.attr1>>objSizeShift]
+	return s[0], s[1]
+}
+
+func (o objAttrs) mode() int {
+	return int(o.attr0>>objModeShift) & 0x3
+}
+
+func (o objAttrs) priority() uint8 {
+	return uint8(o.attr2>>10) & 0x3
+}
+
+// position 返回屏幕坐标，Y 在 255 处回绕，X 在 511 处回绕
+func (o objAttrs) position() (int, int) {
+	x := int(o.attr1 & 0x1FF)
+	y := int(o.attr0 & 0xFF)
+	if x >= ScreenWidth {
+		x -= 512
+	}
+	if y >= ScreenHeight {
+		y -= 256
+	}
+	return x, y
+}
+
+func (p *PPU) clearObjLine() {
+	for x := 0; x < ScreenWidth; x++ {
+		p.objLine[x] = transparent
+		p.objPrio[x] = 4
+		p.objSemi[x] = false
+		p.objWindow[x] = false
+	}
+}
+
+// renderObjects 按 OAM 顺序绘制当前扫描线上的精灵，超出每行周期预算的精灵被丢弃
+func (p *PPU) renderObjects() {
+	p.clearObjLine()
+	if p.DISPCNT&ObjEnable == 0 {
+		return
+	}
+
+	budget := objCycles
+	if p.DISPCNT&HBlankFree != 0 {
+		budget = objCyclesHBF
+	}
+	bitmap := isBitmapMode(p.DISPCNT & BGModeMask)
+
+	for i := 0; i < objCount; i++ {
+		obj := p.readObj(i)
+		if obj.attr0&objAffine == 0 && obj.attr0&objDisable != 0 {
+			continue
+		}
+		if obj.mode() == 3 {
+			continue
+		}
+		// 位图模式下前 512 个图块被位图占用，使用它们的精灵不显示
+		if bitmap && obj.attr2&0x3FF < bitmapObjTileBase {
+			continue
+		}
+
+		width, height := obj.size()
+		affine := obj.attr0&objAffine != 0
+
+		// 双倍尺寸的仿射精灵占用两倍的显示区域
+		if affine && obj.attr0&objDisable != 0 {
+			width, height = width*2, height*2
+		}
+
+		_, y := obj.position()
+		dy := (p.CurrentLine - y) & 0xFF
+		if dy >= height {
+			continue
+		}
+
+		cost := width
+		if affine {
+			cost = 10 + width*2
+		}
+		if budget < cost {
+			break
+		}
+		budget -= cost
+
+		if affine {
+			p.renderAffineObj(obj, dy, width, height)
+		} else {
+			p.renderRegularObj(obj, dy)
+		}
+	}
+}
+
+// objTileAddr 返回精灵第 (tx, ty) 个图块的 VRAM 地址
+func (p *PPU) objTileAddr(obj objAttrs, tx, ty, width int) int {
+	tile := int(obj.attr2 & 0x3FF)
+	color256 := obj.attr0&obj256Color != 0
+
+	// 8bpp 图块占两个 32 字节单元
+	step := 1
+	if color256 {
+		step = 2
+	}
+
+	if p.DISPCNT&Obj1DMap != 0 {
+		tile += ty*(width/8)*step + tx*step
+	} else {
+		if color256 {
+			tile &^= 1
+		}
+		tile += ty*32 + tx*step
+	}
+
+	return objCharBase + (tile&0x3FF)*32
+}
+
+// objPixel 返回精灵纹理坐标 (sx, sy) 处的颜色索引，0 表示透明
+func (p *PPU) objPixel(obj objAttrs, sx, sy, width int) uint8 {
+	addr := p.objTileAddr(obj, sx/8, sy/8, width)
+	px, py := sx%8, sy%8
+
+	if obj.attr0&obj256Color != 0 {
+		addr += py*8 + px
+		if addr >= len(p.VRAM) {
+			return 0
+		}
+		return p.VRAM[addr]
+	}
+
+	addr += py*4 + px/2
+	if addr >= len(p.VRAM) {
+		return 0
+	}
+	return (p.VRAM[addr] >> uint((px&1)*4)) & 0xF
+}
+
+func (p *PPU) objColor(obj objAttrs, idx uint8) uint16 {
+	if obj.attr0&obj256Color == 0 {
+		idx += uint8(obj.attr2>>12) * 16
+	}
+	offset := objPalette + int(idx)*2
+	return (uint16(p.Palette[offset]) | uint16(p.Palette[offset+1])<<8) & 0x7FFF
+}
+
+func (p *PPU) renderRegularObj(obj objAttrs, dy int) {
+	width, height := obj.size()
+	x0, _ := obj.position()
+	mosaic := obj.attr0&objMosaic != 0
+	if mosaic {
+		dy = p.objMosaicDY(dy)
+	}
+
+	sy := dy
+	if obj.attr1&objVFlip != 0 {
+		sy = height - 1 - dy
+	}
+
+	for i := 0; i < width; i++ {
+		x := x0 + i
+		if x < 0 || x >= ScreenWidth {
+			continue
+		}
+
+		sx := i
+		if mosaic {
+			sx = p.objMosaicX(x, x0) - x0
+		}
+		if obj.attr1&objHFlip != 0 {
+			sx = width - 1 - sx
+		}
+
+		idx := p.objPixel(obj, sx, sy, width)
+		if idx == 0 {
+			continue
+		}
+		p.plotObj(obj, x, idx)
+	}
+}
+
+// objMatrix 返回精灵使用的仿射参数组，PA-PD 分散在 4 个 OAM 项的第 4 个半字
+func (p *PPU) objMatrix(obj objAttrs) (pa, pb, pc, pd int) {
+	base := int((obj.attr1>>objAffineShift)&0x1F) * 32
+	read := func(offset int) int {
+		return int(int16(uint16(p.OAM[base+offset]) | uint16(p.OAM[base+offset+1])<<8))
+	}
+	return read(6), read(14), read(22), read(30)
+}
+
+// renderAffineObj 绘制仿射精灵，boxW/boxH 为显示区域（双倍尺寸时是纹理的两倍）
+func (p *PPU) renderAffineObj(obj objAttrs, dy, boxW, boxH int) {
+	width, height := obj.size()
+	x0, _ := obj.position()
+	pa, pb, pc, pd := p.objMatrix(obj)
+	mosaic := obj.attr0&objMosaic != 0
+	if mosaic {
+		dy = p.objMosaicDY(dy)
+	}
+
+	// 以显示区域中心为原点，8.8 定点数采样
+	iy := dy - boxH/2
+	for i := 0; i < boxW; i++ {
+		x := x0 + i
+		if x < 0 || x >= ScreenWidth {
+			continue
+		}
+
+		ix := i - boxW/2
+		if mosaic {
+			ix = p.objMosaicX(x, x0) - x0 - boxW/2
+		}
+		sx := (pa*ix+pb*iy)>>8 + width/2
+		sy := (pc*ix+pd*iy)>>8 + height/2
+		if sx < 0 || sy < 0 || sx >= width || sy >= height {
+			continue
+		}
+
+		idx := p.objPixel(obj, sx, sy, width)
+		if idx == 0 {
+			continue
+		}
+		p.plotObj(obj, x, idx)
+	}
+}
+
+// plotObj 写入精灵行缓冲，优先级数值更小的精灵覆盖已有像素，相同时 OAM 编号小的在上
+func (p *PPU) plotObj(obj objAttrs, x int, idx uint8) {
+	if obj.mode() == objModeWindow {
+		p.objWindow[x] = true
+		return
+	}
+
+	prio := obj.priority()
+	if p.objLine[x]&transparent == 0 && prio >= p.objPrio[x] {
+		return
+	}
+
+	p.objLine[x] = p.objColor(obj, idx)
+	p.objPrio[x] = prio
+	p.objSemi[x] = obj.mode() == objModeSemi
+}
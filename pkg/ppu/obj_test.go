@@ -0,0 +1,99 @@
+package ppu
+
+import "testing"
+
+func (p *PPU) setObj(i int, attr0, attr1, attr2 uint16) {
+	for j, v := range []uint16{attr0, attr1, attr2} {
+		p.OAM[i*8+j*2] = uint8(v)
+		p.OAM[i*8+j*2+1] = uint8(v >> 8)
+	}
+}
+
+// newObjPPU 准备只显示精灵的模式 0 画面，OAM 中的精灵全部禁用
+func newObjPPU(dispcnt uint16) *PPU {
+	p := newTestPPU()
+	p.setColor(0, testBlue)
+	p.setColor(256+1, testRed)
+	p.setColor(256+2, testGreen)
+	for i := 0; i < objCount; i++ {
+		p.setObj(i, objDisable, 0, 0)
+	}
+	p.WriteRegister(0x04000000, Mode0|ObjEnable|dispcnt)
+	return p
+}
+
+func TestObjTileMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		dispcnt uint16
+		want    uint16
+	}{
+		// 16x16 精灵的第二行图块：1D 映射紧接在第一行之后，2D 映射在下一行图块（+32）
+		{"1D", Obj1DMap, testRed},
+		{"2D", 0, testGreen},
+	}
+
+	for _, tt := range tests {
+		p := newObjPPU(tt.dispcnt)
+		p.fillTile4(objCharBase+2*32, solid(1))
+		p.fillTile4(objCharBase+32*32, solid(2))
+
+		// 16x16、4bpp，位于 (0, 0)
+		p.setObj(0, 0, 1<<objSizeShift, 0)
+
+		p.runLines(9)
+		expectRow(t, tt.name+" top", p.row(0), span{0, 16, testBlue})
+		expectRow(t, tt.name+" bottom", p.row(8), span{0, 8, tt.want}, span{8, 240, testBlue})
+	}
+}
+
+func TestObjCycleBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		before  int
+		dispcnt uint16
+		visible bool
+	}{
+		// 每个 64 宽的普通精灵花费 64 周期，每行共 1210 周期
+		{"17 before", 17, 0, true},
+		{"18 before", 18, 0, false},
+		// HBlank 间隔允许访问 OAM 时只剩 954 周期
+		{"13 before, H-blank free", 13, HBlankFree, true},
+		{"14 before, H-blank free", 14, HBlankFree, false},
+	}
+
+	for _, tt := range tests {
+		p := newObjPPU(Obj1DMap | tt.dispcnt)
+		p.fillTile4(objCharBase, solid(1))
+
+		// 前面的精灵叠在 x=0，最后一个放在 x=100
+		// 不在当前行的精灵不占用预算
+		p.setObj(0, 100, 3<<objSizeShift, 0)
+		for i := 1; i <= tt.before; i++ {
+			p.setObj(i, 0, 3<<objSizeShift, 0)
+		}
+		p.setObj(tt.before+1, 0, 3<<objSizeShift|100, 0)
+
+		p.runLines(1)
+		var want uint16 = testBlue
+		if tt.visible {
+			want = testRed
+		}
+		expectRow(t, tt.name, p.row(0), span{0, 1, testRed}, span{100, 101, want})
+	}
+}
+
+func TestObjPriorityOrder(t *testing.T) {
+	p := newObjPPU(Obj1DMap)
+	p.fillTile4(objCharBase, solid(1))
+	p.fillTile4(objCharBase+32, solid(2))
+
+	// OAM 编号小的精灵在上；优先级数值小的精灵在上
+	p.setObj(0, 0, 0, 0)
+	p.setObj(1, 0, 0, 1)
+	p.setObj(2, 0, 16, 1)
+	p.setObj(3, 0, 16, 1<<10)
+
+	p.runLines(1)
+	expectRow(t, "priority", p.row(0), span{0, 8, testRed}, span{16, 24, testGreen})
+}
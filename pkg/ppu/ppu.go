@@ -3,11 +3,9 @@ package ppu
 import (
 	"fmt"
 	"image"
-	"image/color"
+	"sync/atomic"
 )
 
-var _ = color.RGBA{}
-
 const (
 	ScreenWidth  = 240
 	ScreenHeight = 160
@@ -39,6 +37,18 @@ const (
 	VBlankIRQ  = 0x0008
 	HBlankIRQ  = 0x0010
 	VCountIRQ  = 0x0020
+
+	// IF 中的 LCD 中断位
+	IRQVBlank = 0x0001
+	IRQHBlank = 0x0002
+	IRQVCount = 0x0004
+)
+
+// 每行 1232 周期，其中前 960 周期为 HDraw；每帧 228 行
+const (
+	hdrawCycles = 960
+	lineCycles  = 1232
+	totalLines  = 228
 )
 
 type PPU struct {
@@ -46,9 +56,10 @@ type PPU struct {
 	Palette []byte
 	OAM     []byte
 
-	DISPCNT  uint16
-	DISPSTAT uint16
-	VCOUNT   uint16
+	DISPCNT   uint16
+	GREENSWAP uint16
+	DISPSTAT  uint16
+	VCOUNT    uint16
 
 	BG0CNT  uint16
 	BG1CNT  uint16
@@ -92,218 +103,261 @@ type PPU struct {
 	BLDALPHA uint16
 	BLDY     uint16
 
+	// FrameBuffer 是正在绘制的帧，只能在模拟线程访问，其他线程使用 CopyFrame
 	FrameBuffer []uint16
-	CurrentLine int
-	CycleCount  int
+	frames      frameHandoff
+	// ToImage 使用的颜色校正方案，GUI 线程可能随时修改
+	colorProfile atomic.Int32
+	bgLine       [4][ScreenWidth]uint16
+	objLine      [ScreenWidth]uint16
+	objPrio      [ScreenWidth]uint8
+	objSemi      [ScreenWidth]bool
+	objWindow    [ScreenWidth]bool
+	windowMask   [ScreenWidth]uint8
+
+	bgMosaicY      int
+	bgMosaicCount  int
+	objMosaicY     int
+	objMosaicCount int
+	bg2MosaicX     int32
+	bg2MosaicY     int32
+	bg3MosaicX     int32
+	bg3MosaicY     int32
+	CurrentLine    int
+	CycleCount     int
 
 	HBlank bool
 	VBlank bool
+
+	RequestInterrupt func(irq uint16)
+
+	// 进入 HBlank / VBlank 时调用，用于启动对应时机的 DMA
+	OnHBlank func()
+	OnVBlank func()
 }
 
-func New(vram, palette, oam []byte) *PPU {
+func New(vram, palette, oam []byte, requestIRQ func(uint16)) *PPU {
 	ppu := &PPU{
-		VRAM:        vram,
-		Palette:     palette,
-		OAM:         oam,
-		FrameBuffer: make([]uint16, ScreenWidth*ScreenHeight),
+		VRAM:             vram,
+		Palette:          palette,
+		OAM:              oam,
+		FrameBuffer:      make([]uint16, ScreenWidth*ScreenHeight),
+		RequestInterrupt: requestIRQ,
 	}
+	ppu.frames.ready = make([]uint16, ScreenWidth*ScreenHeight)
+	ppu.frames.front = make([]uint16, ScreenWidth*ScreenHeight)
 	ppu.Reset()
 	return ppu
 }
 
 func (p *PPU) Reset() {
 	p.DISPCNT = 0x0080
+	p.GREENSWAP = 0
 	p.DISPSTAT = 0x0000
 	p.VCOUNT = 0x0000
 	p.CurrentLine = 0
 	p.CycleCount = 0
 	p.HBlank = false
 	p.VBlank = false
+	p.updateVCountFlag()
+
+	p.BG2PA, p.BG2PB, p.BG2PC, p.BG2PD = 0x100, 0, 0, 0x100
+	p.BG3PA, p.BG3PB, p.BG3PC, p.BG3PD = 0x100, 0, 0, 0x100
+	p.BG2X, p.BG2Y, p.BG3X, p.BG3Y = 0, 0, 0, 0
+	p.MOSAIC = 0
+	p.latchAffine()
+	p.resetMosaic()
 
 	for i := range p.FrameBuffer {
 		p.FrameBuffer[i] = 0
 	}
+	p.resetFrames()
 
 	fmt.Printf("[PPU] Reset complete. DISPCNT: 0x%04X (ForcedBlank: %v)\n",
 		p.DISPCNT, p.DISPCNT&ForcedBlank != 0)
 }
 
+// Step 推进扫描线时序，进入 VBlank（一帧画完）时返回 true
 func (p *PPU) Step(cycles int) bool {
 	p.CycleCount += cycles
+	frameDone := false
 
-	if p.VBlank {
-		if p.CycleCount >= 1232 {
-			p.CycleCount -= 1232
-			p.CurrentLine++
-			p.VCOUNT = uint16(p.CurrentLine)
-
-			if p.CurrentLine >= 228 {
-				p.CurrentLine = 0
-				p.VCOUNT = 0
-				p.VBlank = false
-				p.DISPSTAT &^= VBlankFlag
-				return true
+	for {
+		if !p.HBlank {
+			if p.CycleCount < hdrawCycles {
+				break
 			}
-		}
-	} else if p.HBlank {
-		if p.CycleCount >= 272 {
-			p.CycleCount -= 272
-			p.HBlank = false
-			p.DISPSTAT &^= HBlankFlag
-			p.CurrentLine++
-			p.VCOUNT = uint16(p.CurrentLine)
-
-			if p.CurrentLine >= 160 {
-				p.VBlank = true
-				p.DISPSTAT |= VBlankFlag
-				if p.DISPSTAT&VBlankIRQ != 0 {
-					return true
-				}
-			}
-		}
-	} else {
-		if p.CycleCount >= 960 {
-			p.CycleCount -= 960
-			p.HBlank = true
-			p.DISPSTAT |= HBlankFlag
-
-			if p.DISPSTAT&HBlankIRQ != 0 {
-				return true
+			p.enterHBlank()
+		} else {
+			if p.CycleCount < lineCycles {
+				break
 			}
-
-			if !p.VBlank {
-				p.RenderScanline()
+			p.CycleCount -= lineCycles
+			if p.nextLine() {
+				frameDone = true
 			}
 		}
 	}
 
-	return false
+	return frameDone
 }
 
-func (p *PPU) RenderScanline() {
-	mode := p.DISPCNT & BGModeMask
+func (p *PPU) enterHBlank() {
+	p.HBlank = true
+	p.DISPSTAT |= HBlankFlag
 
-	if p.DISPCNT&ForcedBlank != 0 {
-		// 强制空白 - 填充白色
-		for x := 0; x < ScreenWidth; x++ {
-			p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = 0x7FFF
-		}
-		return
+	if p.CurrentLine < ScreenHeight {
+		p.RenderScanline()
+		p.advanceAffine()
+		p.stepMosaic()
 	}
 
-	switch mode {
-	case Mode0:
-		p.renderMode0()
-	case Mode1:
-		p.renderMode1()
-	case Mode2:
-		p.renderMode2()
-	case Mode3:
-		p.renderMode3()
-	case Mode4:
-		p.renderMode4()
-	case Mode5:
-		p.renderMode5()
+	// VBlank 期间 HBlank 标志和中断照常产生，但 HBlank DMA 只在可见行启动
+	if p.DISPSTAT&HBlankIRQ != 0 {
+		p.requestIRQ(IRQHBlank)
 	}
-}
-
-func (p *PPU) renderMode0() {
-	for x := 0; x < ScreenWidth; x++ {
-		p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = p.getPaletteColor(0)
+	if p.CurrentLine < ScreenHeight && p.OnHBlank != nil {
+		p.OnHBlank()
 	}
 }
 
-func (p *PPU) renderMode1() {
-	for x := 0; x < ScreenWidth; x++ {
-		p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = p.getPaletteColor(0)
-	}
-}
+// nextLine 结束 HBlank 进入下一行，返回是否刚进入 VBlank
+func (p *PPU) nextLine() bool {
+	p.HBlank = false
+	p.DISPSTAT &^= HBlankFlag
 
-func (p *PPU) renderMode2() {
-	for x := 0; x < ScreenWidth; x++ {
-		p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = p.getPaletteColor(0)
+	p.CurrentLine++
+	if p.CurrentLine >= totalLines {
+		p.CurrentLine = 0
 	}
-}
-
-func (p *PPU) renderMode3() {
-	if p.DISPCNT&FrameSelect != 0 {
-		return
+	p.VCOUNT = uint16(p.CurrentLine)
+
+	vblankStart := false
+	switch p.CurrentLine {
+	case ScreenHeight:
+		p.VBlank = true
+		p.DISPSTAT |= VBlankFlag
+		p.publishFrame()
+		p.latchAffine()
+		p.resetMosaic()
+		if p.DISPSTAT&VBlankIRQ != 0 {
+			p.requestIRQ(IRQVBlank)
+		}
+		if p.OnVBlank != nil {
+			p.OnVBlank()
+		}
+		vblankStart = true
+	case totalLines - 1:
+		// 最后一行 VBlank 标志已清除
+		p.VBlank = false
+		p.DISPSTAT &^= VBlankFlag
 	}
 
-	for x := 0; x < ScreenWidth; x++ {
-		offset := (p.CurrentLine*ScreenWidth + x) * 2
-		if offset+1 < len(p.VRAM) {
-			color := uint16(p.VRAM[offset]) | uint16(p.VRAM[offset+1])<<8
-			p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = color
-		}
+	if p.updateVCountFlag() && p.DISPSTAT&VCountIRQ != 0 {
+		p.requestIRQ(IRQVCount)
 	}
+
+	return vblankStart
 }
 
-func (p *PPU) renderMode4() {
-	frameOffset := 0
-	if p.DISPCNT&FrameSelect != 0 {
-		frameOffset = 0xA000
+// updateVCountFlag 比较 VCOUNT 与 DISPSTAT 高 8 位的 LYC，返回是否匹配
+func (p *PPU) updateVCountFlag() bool {
+	if p.VCOUNT == p.DISPSTAT>>8 {
+		p.DISPSTAT |= VCountFlag
+		return true
 	}
+	p.DISPSTAT &^= VCountFlag
+	return false
+}
 
-	for x := 0; x < ScreenWidth; x++ {
-		offset := frameOffset + p.CurrentLine*ScreenWidth + x
-		if offset < len(p.VRAM) {
-			paletteIdx := p.VRAM[offset]
-			p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = p.getPaletteColor(paletteIdx)
-		}
+func (p *PPU) requestIRQ(irq uint16) {
+	if p.RequestInterrupt != nil {
+		p.RequestInterrupt(irq)
 	}
 }
 
-func (p *PPU) renderMode5() {
-	if p.CurrentLine >= 128 {
+func (p *PPU) RenderScanline() {
+	if p.DISPCNT&ForcedBlank != 0 {
+		// 强制空白 - 填充白色
 		for x := 0; x < ScreenWidth; x++ {
-			p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = 0
+			p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = 0x7FFF
 		}
 		return
 	}
 
-	frameOffset := 0
-	if p.DISPCNT&FrameSelect != 0 {
-		frameOffset = 0xA000
-	}
+	mode := p.DISPCNT & BGModeMask
+	p.renderObjects()
+	p.buildWindowMask()
 
-	for x := 0; x < 160; x++ {
-		offset := frameOffset + (p.CurrentLine*160+x)*2
-		if offset+1 < len(p.VRAM) {
-			color := uint16(p.VRAM[offset]) | uint16(p.VRAM[offset+1])<<8
-			if x < ScreenWidth {
-				p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = color
-			}
+	bgs := modeLayers[mode]
+	for _, bg := range bgs {
+		if p.bgEnabled(bg) {
+			p.renderBG(mode, bg)
 		}
 	}
+	p.composeLine(bgs...)
+
+	if p.GREENSWAP&1 != 0 {
+		applyGreenSwap(p.FrameBuffer[p.CurrentLine*ScreenWidth : (p.CurrentLine+1)*ScreenWidth])
+	}
+}
+
+// 各显示模式可用的背景层，模式 6/7 无效，只显示精灵和背景色
+var modeLayers = [8][]int{
+	Mode0: {0, 1, 2, 3},
+	Mode1: {0, 1, 2},
+	Mode2: {2, 3},
+	Mode3: {2},
+	Mode4: {2},
+	Mode5: {2},
+}
 
-	for x := 160; x < ScreenWidth; x++ {
-		p.FrameBuffer[p.CurrentLine*ScreenWidth+x] = 0
+// renderBG 将背景 bg 的当前扫描线画到 bgLine[bg]
+func (p *PPU) renderBG(mode uint16, bg int) {
+	switch {
+	case mode == Mode0 || (mode == Mode1 && bg < 2):
+		p.renderTextBG(bg)
+	case mode == Mode1 || mode == Mode2:
+		p.renderAffineBG(bg)
+	default:
+		p.renderBitmapBG(mode)
 	}
 }
 
 func (p *PPU) getPaletteColor(idx uint8) uint16 {
 	offset := uint32(idx) * 2
 	if offset+1 < uint32(len(p.Palette)) {
-		return uint16(p.Palette[offset]) | uint16(p.Palette[offset+1])<<8
+		return (uint16(p.Palette[offset]) | uint16(p.Palette[offset+1])<<8) & 0x7FFF
 	}
 	return 0
 }
 
+// GetFrameBuffer 返回正在绘制的帧，只能在模拟线程使用
 func (p *PPU) GetFrameBuffer() []uint16 {
 	return p.FrameBuffer
 }
 
+// ToImage 返回最近一帧完整画面
 func (p *PPU) ToImage() *image.RGBA {
+	img, _ := p.FrameImage()
+	return img
+}
+
+// FrameImage 返回最近一帧完整画面和它的帧序号
+func (p *PPU) FrameImage() (*image.RGBA, uint64) {
+	frame := make([]uint16, ScreenWidth*ScreenHeight)
+	seq := p.CopyFrame(frame)
+	return FrameToImage(frame, p.ColorProfile()), seq
+}
+
+// FrameToImage 按颜色校正方案把 240x160 的 BGR555 帧转换为图像
+func FrameToImage(frame []uint16, profile ColorProfile) *image.RGBA {
 	img := image.NewRGBA(image.Rect(0, 0, ScreenWidth, ScreenHeight))
+	table := ColorTable(profile)
 
 	for y := 0; y < ScreenHeight; y++ {
 		for x := 0; x < ScreenWidth; x++ {
-			col := p.FrameBuffer[y*ScreenWidth+x]
-			r := uint8((col & 0x1F) << 3)
-			g := uint8(((col >> 5) & 0x1F) << 3)
-			b := uint8(((col >> 10) & 0x1F) << 3)
-			img.SetRGBA(x, y, color.RGBA{r, g, b, 255})
+			img.SetRGBA(x, y, table[frame[y*ScreenWidth+x]&0x7FFF])
 		}
 	}
 
@@ -322,7 +376,8 @@ func (p *PPU) SetDISPCNT(val uint16) {
 }
 
 func (p *PPU) SetDISPSTAT(val uint16) {
-	p.DISPSTAT = (p.DISPSTAT & 0x0007) | (val & 0xFFF8)
+	p.DISPSTAT = (p.DISPSTAT & 0x0007) | (val & 0xFF38)
+	p.updateVCountFlag()
 }
 
 func (p *PPU) GetVCOUNT() uint16 {
@@ -341,10 +396,14 @@ func (p *PPU) IsHBlank() bool {
 	return p.HBlank
 }
 
+// ReadRegister 返回寄存器当前保存的值，包括游戏无法读取的只写寄存器，
+// 供 MMU 做字节写入时的读-改-写；游戏可见的读取由 MMU 过滤
 func (p *PPU) ReadRegister(addr uint32) uint16 {
 	switch addr {
 	case 0x04000000:
 		return p.DISPCNT
+	case 0x04000002:
+		return p.GREENSWAP
 	case 0x04000004:
 		return p.DISPSTAT
 	case 0x04000006:
@@ -357,6 +416,74 @@ func (p *PPU) ReadRegister(addr uint32) uint16 {
 		return p.BG2CNT
 	case 0x0400000E:
 		return p.BG3CNT
+	case 0x04000010:
+		return p.BG0HOFS
+	case 0x04000012:
+		return p.BG0VOFS
+	case 0x04000014:
+		return p.BG1HOFS
+	case 0x04000016:
+		return p.BG1VOFS
+	case 0x04000018:
+		return p.BG2HOFS
+	case 0x0400001A:
+		return p.BG2VOFS
+	case 0x0400001C:
+		return p.BG3HOFS
+	case 0x0400001E:
+		return p.BG3VOFS
+	case 0x04000020:
+		return uint16(p.BG2PA)
+	case 0x04000022:
+		return uint16(p.BG2PB)
+	case 0x04000024:
+		return uint16(p.BG2PC)
+	case 0x04000026:
+		return uint16(p.BG2PD)
+	case 0x04000028:
+		return uint16(p.BG2X)
+	case 0x0400002A:
+		return uint16(uint32(p.BG2X)>>16) & 0x0FFF
+	case 0x0400002C:
+		return uint16(p.BG2Y)
+	case 0x0400002E:
+		return uint16(uint32(p.BG2Y)>>16) & 0x0FFF
+	case 0x04000030:
+		return uint16(p.BG3PA)
+	case 0x04000032:
+		return uint16(p.BG3PB)
+	case 0x04000034:
+		return uint16(p.BG3PC)
+	case 0x04000036:
+		return uint16(p.BG3PD)
+	case 0x04000038:
+		return uint16(p.BG3X)
+	case 0x0400003A:
+		return uint16(uint32(p.BG3X)>>16) & 0x0FFF
+	case 0x0400003C:
+		return uint16(p.BG3Y)
+	case 0x0400003E:
+		return uint16(uint32(p.BG3Y)>>16) & 0x0FFF
+	case 0x04000040:
+		return p.WIN0H
+	case 0x04000042:
+		return p.WIN1H
+	case 0x04000044:
+		return p.WIN0V
+	case 0x04000046:
+		return p.WIN1V
+	case 0x04000048:
+		return p.WININ
+	case 0x0400004A:
+		return p.WINOUT
+	case 0x0400004C:
+		return p.MOSAIC
+	case 0x04000050:
+		return p.BLDCNT
+	case 0x04000052:
+		return p.BLDALPHA
+	case 0x04000054:
+		return p.BLDY
 	default:
 		return 0
 	}
@@ -365,7 +492,9 @@ func (p *PPU) ReadRegister(addr uint32) uint16 {
 func (p *PPU) WriteRegister(addr uint32, val uint16) {
 	switch addr {
 	case 0x04000000:
-		p.DISPCNT = val
+		p.SetDISPCNT(val)
+	case 0x04000002:
+		p.GREENSWAP = val & 1
 	case 0x04000004:
 		p.SetDISPSTAT(val)
 	case 0x04000008:
@@ -392,5 +521,64 @@ func (p *PPU) WriteRegister(addr uint32, val uint16) {
 		p.BG3HOFS = val
 	case 0x0400001E:
 		p.BG3VOFS = val
+	case 0x04000020:
+		p.BG2PA = int16(val)
+	case 0x04000022:
+		p.BG2PB = int16(val)
+	case 0x04000024:
+		p.BG2PC = int16(val)
+	case 0x04000026:
+		p.BG2PD = int16(val)
+	case 0x04000028:
+		p.setAffineOrigin(&p.BG2X, &p.BG2RefX, &p.bg2MosaicX, uint32(val), 0xFFFF0000)
+	case 0x0400002A:
+		p.setAffineOrigin(&p.BG2X, &p.BG2RefX, &p.bg2MosaicX, uint32(val)<<16, 0x0000FFFF)
+	case 0x0400002C:
+		p.setAffineOrigin(&p.BG2Y, &p.BG2RefY, &p.bg2MosaicY, uint32(val), 0xFFFF0000)
+	case 0x0400002E:
+		p.setAffineOrigin(&p.BG2Y, &p.BG2RefY, &p.bg2MosaicY, uint32(val)<<16, 0x0000FFFF)
+	case 0x04000030:
+		p.BG3PA = int16(val)
+	case 0x04000032:
+		p.BG3PB = int16(val)
+	case 0x04000034:
+		p.BG3PC = int16(val)
+	case 0x04000036:
+		p.BG3PD = int16(val)
+	case 0x04000038:
+		p.setAffineOrigin(&p.BG3X, &p.BG3RefX, &p.bg3MosaicX, uint32(val), 0xFFFF0000)
+	case 0x0400003A:
+		p.setAffineOrigin(&p.BG3X, &p.BG3RefX, &p.bg3MosaicX, uint32(val)<<16, 0x0000FFFF)
+	case 0x0400003C:
+		p.setAffineOrigin(&p.BG3Y, &p.BG3RefY, &p.bg3MosaicY, uint32(val), 0xFFFF0000)
+	case 0x0400003E:
+		p.setAffineOrigin(&p.BG3Y, &p.BG3RefY, &p.bg3MosaicY, uint32(val)<<16, 0x0000FFFF)
+	case 0x04000040:
+		p.WIN0H = val
+	case 0x04000042:
+		p.WIN1H = val
+	case 0x04000044:
+		p.WIN0V = val
+	case 0x04000046:
+		p.WIN1V = val
+	case 0x04000048:
+		p.WININ = val & 0x3F3F
+	case 0x0400004A:
+		p.WINOUT = val & 0x3F3F
+	case 0x0400004C:
+		p.MOSAIC = val
+	case 0x04000050:
+		p.BLDCNT = val & 0x3FFF
+	case 0x04000052:
+		p.BLDALPHA = val & 0x1F1F
+	case 0x04000054:
+		p.BLDY = val & 0x1F
 	}
 }
+
+// setAffineOrigin 写入 BGxX/BGxY 的一半，同时立即重新装入内部参考点和马赛克取样点
+func (p *PPU) setAffineOrigin(reg, ref, mosaic *int32, val, keep uint32) {
+	*reg = signExtend28(uint32(*reg)&keep | val)
+	*ref = *reg
+	*mosaic = *reg
+}
@@ -0,0 +1,68 @@
+package ppu
+
+import "testing"
+
+func newTestPPU() *PPU {
+	return New(make([]byte, 0x18000), make([]byte, 0x400), make([]byte, 0x400), nil)
+}
+
+// runLines 从第 0 行开始按正常时序走完 n 条扫描线，每行在进入 HBlank 时绘制
+func (p *PPU) runLines(n int) {
+	for i := 0; i < n; i++ {
+		p.Step(lineCycles)
+	}
+}
+
+func (p *PPU) row(y int) []uint16 {
+	return p.FrameBuffer[y*ScreenWidth : (y+1)*ScreenWidth]
+}
+
+func (p *PPU) setColor(idx int, c uint16) {
+	p.Palette[idx*2] = uint8(c)
+	p.Palette[idx*2+1] = uint8(c >> 8)
+}
+
+func (p *PPU) write16(addr int, val uint16) {
+	p.VRAM[addr] = uint8(val)
+	p.VRAM[addr+1] = uint8(val >> 8)
+}
+
+// fillTile4 把 4bpp 图块的每个像素设为 pixel(x, y) 返回的颜色索引
+func (p *PPU) fillTile4(addr int, pixel func(x, y int) uint8) {
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x += 2 {
+			p.VRAM[addr+y*4+x/2] = pixel(x, y) | pixel(x+1, y)<<4
+		}
+	}
+}
+
+// fillTile8 把 8bpp 图块的每个像素设为 pixel(x, y) 返回的颜色索引
+func (p *PPU) fillTile8(addr int, pixel func(x, y int) uint8) {
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			p.VRAM[addr+y*8+x] = pixel(x, y)
+		}
+	}
+}
+
+func solid(idx uint8) func(x, y int) uint8 {
+	return func(x, y int) uint8 { return idx }
+}
+
+// span 描述一段扫描线上期望的颜色 [from, to)
+type span struct {
+	from, to int
+	color    uint16
+}
+
+func expectRow(t *testing.T, name string, row []uint16, spans ...span) {
+	t.Helper()
+	for _, s := range spans {
+		for x := s.from; x < s.to; x++ {
+			if row[x] != s.color {
+				t.Errorf("%s: x=%d color %04X, want %04X", name, x, row[x], s.color)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package ppu
+
+// 窗口控制位：bit0-3 = BG0-BG3, bit4 = OBJ, bit5 = 颜色特效
+const (
+	layerOBJ     = 0x10
+	layerEffects = 0x20
+	layerAll     = 0x3F
+)
+
+// inWindowRange 判断坐标是否落在 [start, end) 内，start > end 时区间回绕
+func inWindowRange(pos int, reg uint16, limit int) bool {
+	start := int(reg >> 8)
+	end := int(reg & 0xFF)
+	if start > end {
+		return pos >= start || pos < end
+	}
+	if end > limit {
+		end = limit
+	}
+	return pos >= start && pos < end
+}
+
+// buildWindowMask 计算当前扫描线每个像素可见的图层和是否允许颜色特效
+func (p *PPU) buildWindowMask() {
+	if p.DISPCNT&(Win0Enable|Win1Enable|ObjWinEnable) == 0 {
+		for x := range p.windowMask {
+			p.windowMask[x] = layerAll
+		}
+		return
+	}
+
+	y := p.CurrentLine
+	win0 := p.DISPCNT&Win0Enable != 0 && inWindowRange(y, p.WIN0V, ScreenHeight)
+	win1 := p.DISPCNT&Win1Enable != 0 && inWindowRange(y, p.WIN1V, ScreenHeight)
+	objWin := p.DISPCNT&ObjWinEnable != 0
+
+	// 优先级 WIN0 > WIN1 > OBJ 窗口 > 窗口外
+	for x := range p.windowMask {
+		switch {
+		case win0 && inWindowRange(x, p.WIN0H, ScreenWidth):
+			p.windowMask[x] = uint8(p.WININ) & layerAll
+		case win1 && inWindowRange(x, p.WIN1H, ScreenWidth):
+			p.windowMask[x] = uint8(p.WININ>>8) & layerAll
+		case objWin && p.objWindow[x]:
+			p.windowMask[x] = uint8(p.WINOUT>>8) & layerAll
+		default:
+			p.windowMask[x] = uint8(p.WINOUT) & layerAll
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package ppu
+
+import "testing"
+
+// newTwoLayerPPU 准备两层铺满全屏的文本背景：BG0 红色在上，BG1 绿色在下
+func newTwoLayerPPU() *PPU {
+	p := newTestPPU()
+	p.setColor(0, testBlue)
+	p.setColor(1, testRed)
+	p.setColor(2, testGreen)
+
+	// 屏幕块全为 0，两个背景分别从不同的字符块取图块 0
+	p.fillTile4(0x0000, solid(1))
+	p.fillTile4(0x4000, solid(2))
+	p.WriteRegister(0x04000008, 31<<bgScreenShift)
+	p.WriteRegister(0x0400000A, 31<<bgScreenShift|1<<bgCharBaseShift|1)
+	return p
+}
+
+func TestWindowPriority(t *testing.T) {
+	p := newTwoLayerPPU()
+	p.WriteRegister(0x04000000, Mode0|BG0Enable|BG1Enable|Win0Enable|Win1Enable)
+
+	// WIN0 = [0, 100) 只显示 BG1，WIN1 = [50, 150) 只显示 BG0，窗口外什么都不显示
+	p.WriteRegister(0x04000040, 0<<8|100)
+	p.WriteRegister(0x04000042, 50<<8|150)
+	p.WriteRegister(0x04000044, 0<<8|160)
+	p.WriteRegister(0x04000046, 0<<8|160)
+	p.WriteRegister(0x04000048, 0x01<<8|0x02)
+	p.WriteRegister(0x0400004A, 0x00)
+
+	p.runLines(1)
+	expectRow(t, "windows", p.row(0),
+		span{0, 100, testGreen},
+		// 重叠部分由 WIN0 决定
+		span{50, 100, testGreen},
+		span{100, 150, testRed},
+		span{150, 240, testBlue})
+}
+
+func TestWindowVerticalRange(t *testing.T) {
+	p := newTwoLayerPPU()
+	p.WriteRegister(0x04000000, Mode0|BG0Enable|BG1Enable|Win0Enable)
+
+	// WIN0 只覆盖第 2 行，范围 [2, 3)；窗口外显示 BG1
+	p.WriteRegister(0x04000040, 0<<8|240)
+	p.WriteRegister(0x04000044, 2<<8|3)
+	p.WriteRegister(0x04000048, 0x01)
+	p.WriteRegister(0x0400004A, 0x02)
+
+	p.runLines(4)
+	for y, want := range []uint16{testGreen, testGreen, testRed, testGreen} {
+		expectRow(t, "line", p.row(y), span{0, 240, want})
+	}
+}
+
+func TestObjWindow(t *testing.T) {
+	p := newTwoLayerPPU()
+	p.WriteRegister(0x04000000, Mode0|BG0Enable|BG1Enable|ObjEnable|Obj1DMap|Win0Enable|ObjWinEnable)
+	for i := 0; i < objCount; i++ {
+		p.setObj(i, objDisable, 0, 0)
+	}
+
+	// 窗口精灵本身不显示，只划出 OBJ 窗口；WIN0 优先于 OBJ 窗口
+	for tile := 0; tile < 4; tile++ {
+		p.fillTile4(objCharBase+tile*32, solid(1))
+	}
+	p.setObj(0, objModeWindow<<objModeShift, 2<<objSizeShift, 0)
+	p.WriteRegister(0x04000040, 0<<8|8)
+	p.WriteRegister(0x04000044, 0<<8|160)
+	p.WriteRegister(0x04000048, 0x01)
+	p.WriteRegister(0x0400004A, 0x02<<8|0x00)
+
+	p.runLines(1)
+	expectRow(t, "obj window", p.row(0),
+		span{0, 8, testRed},
+		span{8, 32, testGreen},
+		span{32, 240, testBlue})
+}